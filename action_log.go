@@ -121,7 +121,7 @@ func (s *ActionLogWriter) Write(ctx context.Context, resource string, action str
 		}
 	}
 	query, vars := BuildInsertSQL(s.Database, s.Table, log, s.BuildParam)
-	_, err := s.Database.ExecContext(ctx, query, vars...)
+	_, err := GetExecutor(ctx, s.Database).ExecContext(ctx, query, vars...)
 	return err
 }
 
@@ -153,7 +153,7 @@ func GetString(ctx context.Context, key string) string {
 	return ""
 }
 func BuildInsertSQL(db *sql.DB, tableName string, model map[string]interface{}, options ...func(i int) string) (string, []interface{}) {
-	driver := GetDriver(db)
+	dialect := GetDialect(db)
 	var buildParam func(i int) string
 	if len(options) > 0 && options[0] != nil {
 		buildParam = options[0]
@@ -163,7 +163,7 @@ func BuildInsertSQL(db *sql.DB, tableName string, model map[string]interface{},
 	var cols []string
 	var values []interface{}
 	for col, v := range model {
-		cols = append(cols, QuoteString(col, driver))
+		cols = append(cols, QuoteName(dialect, col))
 		values = append(values, v)
 	}
 	column := fmt.Sprintf("(%v)", strings.Join(cols, ","))
@@ -174,13 +174,10 @@ func BuildInsertSQL(db *sql.DB, tableName string, model map[string]interface{},
 		arrValue = append(arrValue, param)
 	}
 	value := fmt.Sprintf("(%v)", strings.Join(arrValue, ","))
-	strSQL := fmt.Sprintf("insert into %v %v values %v", QuoteString(tableName, driver), column, value)
+	strSQL := fmt.Sprintf("insert into %v %v values %v", QuoteName(dialect, tableName), column, value)
 	return strSQL, values
 }
 
 func QuoteString(name string, driver string) string {
-	if driver == DriverPostgres {
-		name = "`" + strings.Replace(name, "`", "``", -1) + "`"
-	}
-	return name
+	return QuoteByDriver(name, driver)
 }
@@ -0,0 +1,251 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	CreatedByTag = "createdby"
+	CreatedAtTag = "createdat"
+	UpdatedByTag = "updatedby"
+	UpdatedAtTag = "updatedat"
+)
+
+// Audit sets the audit fields of the models, which are tagged by createdBy, createdAt, updatedBy and updatedAt in the gorm tag:
+// the created fields on insert if they are not set, and the updated fields on insert, update and patch. The created fields are never updated.
+// The user is the string value of the context key User, as ActionLogConfig.User; the user fields are not set if it is empty.
+// The writers of a database use the context key of Config.AuditUser, or of SetAuditUser.
+type Audit struct {
+	User string
+	Now  func() time.Time
+}
+
+func NewAudit(user string) *Audit {
+	return &Audit{User: user}
+}
+
+var auditUsers sync.Map
+
+// SetAuditUser sets the context key of the user of the audit fields, which are set by the writers of db created after, or removes it if user is empty.
+func SetAuditUser(db *sql.DB, user string) {
+	if len(user) == 0 {
+		auditUsers.Delete(db)
+	} else {
+		auditUsers.Store(db, user)
+	}
+}
+func GetAuditUser(db *sql.DB) string {
+	if user, ok := auditUsers.Load(db); ok {
+		return user.(string)
+	}
+	return ""
+}
+
+type auditField struct {
+	Index  int
+	Column string
+	Json   string
+}
+type auditFields struct {
+	CreatedBy *auditField
+	CreatedAt *auditField
+	UpdatedBy *auditField
+	UpdatedAt *auditField
+}
+
+var auditCache sync.Map
+
+func getAuditFields(modelType reflect.Type) *auditFields {
+	if v, ok := auditCache.Load(modelType); ok {
+		return v.(*auditFields)
+	}
+	fields := &auditFields{}
+	numField := modelType.NumField()
+	for i := 0; i < numField; i++ {
+		field := modelType.Field(i)
+		tag, ok := field.Tag.Lookup("gorm")
+		if !ok {
+			continue
+		}
+		column, exist := GetColumnNameByIndex(modelType, i)
+		if !exist {
+			continue
+		}
+		json, ok := GetJsonNameByIndex(modelType, i)
+		if !ok || len(json) == 0 {
+			json = field.Name
+		}
+		f := &auditField{Index: i, Column: column, Json: json}
+		for _, s := range strings.Split(tag, ";") {
+			switch strings.ToLower(strings.TrimSpace(s)) {
+			case CreatedByTag:
+				fields.CreatedBy = f
+			case CreatedAtTag:
+				fields.CreatedAt = f
+			case UpdatedByTag:
+				fields.UpdatedBy = f
+			case UpdatedAtTag:
+				fields.UpdatedAt = f
+			}
+		}
+	}
+	auditCache.Store(modelType, fields)
+	return fields
+}
+
+// createdColumns returns the columns of the created fields of modelType, which must not be updated.
+func createdColumns(modelType reflect.Type) map[string]bool {
+	columns := make(map[string]bool)
+	if modelType.Kind() != reflect.Struct {
+		return columns
+	}
+	fields := getAuditFields(modelType)
+	if f := fields.CreatedBy; f != nil {
+		columns[f.Column] = true
+	}
+	if f := fields.CreatedAt; f != nil {
+		columns[f.Column] = true
+	}
+	return columns
+}
+
+// isCreatedField returns true if the gorm tag is of a created field, which must not be updated.
+func isCreatedField(tag string) bool {
+	for _, s := range strings.Split(tag, ";") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == CreatedByTag || s == CreatedAtTag {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Audit) now() time.Time {
+	if a.Now != nil {
+		return a.Now()
+	}
+	return time.Now()
+}
+
+// OnInsert sets the created and updated fields of model, which is a pointer to a struct or a slice of them.
+func (a *Audit) OnInsert(ctx context.Context, model interface{}) {
+	a.set(ctx, reflect.ValueOf(model), true)
+}
+
+// OnUpdate sets the updated fields of model, which is a pointer to a struct or a slice of them.
+func (a *Audit) OnUpdate(ctx context.Context, model interface{}) {
+	a.set(ctx, reflect.ValueOf(model), false)
+}
+
+// OnPatch sets the updated fields of model, of which the keys are the json names of modelType, and removes the created fields.
+func (a *Audit) OnPatch(ctx context.Context, model map[string]interface{}, modelType reflect.Type) {
+	a.setMap(ctx, model, modelType, false, false)
+}
+
+// OnPatchColumns sets the updated fields of model, of which the keys are the column names of modelType.
+func (a *Audit) OnPatchColumns(ctx context.Context, model map[string]interface{}, modelType reflect.Type) {
+	a.setMap(ctx, model, modelType, true, false)
+}
+
+// OnSave sets the created fields of model, if they are not set, and the updated fields, for an upsert, which does not update the created columns.
+// The keys of model are the json names of modelType.
+func (a *Audit) OnSave(ctx context.Context, model map[string]interface{}, modelType reflect.Type) {
+	a.setMap(ctx, model, modelType, false, true)
+}
+
+func (a *Audit) set(ctx context.Context, v reflect.Value, insert bool) {
+	if a == nil || !v.IsValid() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			a.set(ctx, v.Elem(), insert)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			a.set(ctx, v.Index(i), insert)
+		}
+	case reflect.Struct:
+		if !v.CanSet() {
+			return
+		}
+		fields := getAuditFields(v.Type())
+		user := GetString(ctx, a.User)
+		now := a.now()
+		if insert {
+			setAuditField(v, fields.CreatedBy, user, true)
+			setAuditField(v, fields.CreatedAt, now, true)
+		}
+		setAuditField(v, fields.UpdatedBy, user, false)
+		setAuditField(v, fields.UpdatedAt, now, false)
+	}
+}
+
+// setAuditField sets the field to value, if value is not empty; the created fields are set only if they are zero.
+func setAuditField(v reflect.Value, f *auditField, value interface{}, created bool) {
+	if f == nil {
+		return
+	}
+	if s, ok := value.(string); ok && len(s) == 0 {
+		return
+	}
+	field := v.Field(f.Index)
+	if created && !field.IsZero() {
+		return
+	}
+	fieldType := field.Type()
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	x := reflect.ValueOf(value)
+	if !x.Type().ConvertibleTo(fieldType) {
+		return
+	}
+	x = x.Convert(fieldType)
+	if field.Kind() == reflect.Ptr {
+		p := reflect.New(fieldType)
+		p.Elem().Set(x)
+		x = p
+	}
+	field.Set(x)
+}
+func (a *Audit) setMap(ctx context.Context, model map[string]interface{}, modelType reflect.Type, byColumn bool, insert bool) {
+	if a == nil || model == nil {
+		return
+	}
+	fields := getAuditFields(modelType)
+	key := func(f *auditField) string {
+		if byColumn {
+			return f.Column
+		}
+		return f.Json
+	}
+	user := GetString(ctx, a.User)
+	now := a.now()
+	if f := fields.CreatedBy; f != nil {
+		if !insert {
+			delete(model, key(f))
+		} else if v, ok := model[key(f)]; (!ok || v == nil || v == "") && len(user) > 0 {
+			model[key(f)] = user
+		}
+	}
+	if f := fields.CreatedAt; f != nil {
+		if !insert {
+			delete(model, key(f))
+		} else if v, ok := model[key(f)]; !ok || v == nil {
+			model[key(f)] = now
+		}
+	}
+	if f := fields.UpdatedBy; f != nil && len(user) > 0 {
+		model[key(f)] = user
+	}
+	if f := fields.UpdatedAt; f != nil {
+		model[key(f)] = now
+	}
+}
@@ -0,0 +1,109 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type auditedItem struct {
+	Id        string     `json:"id" gorm:"column:id;primary_key"`
+	Name      string     `json:"name" gorm:"column:name"`
+	CreatedBy string     `json:"createdBy" gorm:"column:created_by;createdBy"`
+	CreatedAt *time.Time `json:"createdAt" gorm:"column:created_at;createdAt"`
+	UpdatedBy string     `json:"updatedBy" gorm:"column:updated_by;updatedBy"`
+	UpdatedAt *time.Time `json:"updatedAt" gorm:"column:updated_at;updatedAt"`
+}
+
+// openDialect returns a database, which is never connected, of dialect d.
+func openDialect(d Dialect) *sql.DB {
+	db := sql.OpenDB(routeErrorConnector{})
+	SetDialect(db, d)
+	return db
+}
+
+func TestBuildSaveDoesNotUpdateCreatedColumns(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		dialect Dialect
+		insert  []string
+		update  string
+	}{
+		{PostgresDialect{}, []string{`"created_at"`, `"created_by"`}, " DO UPDATE SET "},
+		{CockroachDialect{}, []string{`"created_at"`, `"created_by"`}, " DO UPDATE SET "},
+		{MySQLDialect{}, []string{"`created_at`", "`created_by`"}, " ON DUPLICATE KEY UPDATE "},
+		{MssqlDialect{}, []string{"[created_at]", "[created_by]"}, " WHEN MATCHED THEN UPDATE SET "},
+		{OracleDialect{}, []string{`"CREATED_AT"`, `"CREATED_BY"`}, " WHEN MATCHED THEN UPDATE SET "},
+	}
+	for _, tt := range tests {
+		db := openDialect(tt.dialect)
+		item := &auditedItem{Id: "1", Name: "a", CreatedBy: "u1", CreatedAt: &now, UpdatedBy: "u2", UpdatedAt: &now}
+		query, _, err := BuildSave(db, "items", item)
+		if err != nil {
+			t.Fatalf("%T: BuildSave() error = %v", tt.dialect, err)
+		}
+		i := strings.Index(query, tt.update)
+		if i < 0 {
+			t.Fatalf("%T: BuildSave() = %q, want an update clause %q", tt.dialect, query, tt.update)
+		}
+		insert, update := query[:i], query[i:]
+		if j := strings.Index(update, " WHEN NOT MATCHED "); j >= 0 {
+			insert, update = insert+update[j:], update[:j]
+		}
+		for _, column := range tt.insert {
+			if !strings.Contains(strings.ToUpper(insert), strings.ToUpper(column)) {
+				t.Errorf("%T: BuildSave() = %q, want %s inserted", tt.dialect, query, column)
+			}
+			if strings.Contains(strings.ToUpper(update), strings.ToUpper(column)) {
+				t.Errorf("%T: BuildSave() = %q, want %s not updated", tt.dialect, query, column)
+			}
+		}
+		if !strings.Contains(strings.ToLower(update), "updated_at") {
+			t.Errorf("%T: BuildSave() = %q, want updated_at updated", tt.dialect, query)
+		}
+		db.Close()
+	}
+}
+
+func TestAuditOnSave(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	a := &Audit{User: "userId", Now: func() time.Time { return now }}
+	ctx := context.WithValue(context.Background(), "userId", "u1")
+	model := map[string]interface{}{"id": "1", "name": "a"}
+	a.OnSave(ctx, model, reflect.TypeOf(auditedItem{}))
+	for _, key := range []string{"createdBy", "updatedBy"} {
+		if model[key] != "u1" {
+			t.Errorf("OnSave() %s = %v, want u1", key, model[key])
+		}
+	}
+	for _, key := range []string{"createdAt", "updatedAt"} {
+		if model[key] != now {
+			t.Errorf("OnSave() %s = %v, want %v", key, model[key], now)
+		}
+	}
+	model = map[string]interface{}{"id": "1", "createdBy": "u0", "createdAt": now.Add(-time.Hour)}
+	a.OnSave(ctx, model, reflect.TypeOf(auditedItem{}))
+	if model["createdBy"] != "u0" || model["createdAt"] != now.Add(-time.Hour) {
+		t.Errorf("OnSave() replaced the created fields: %v", model)
+	}
+	a.OnPatch(ctx, model, reflect.TypeOf(auditedItem{}))
+	if _, ok := model["createdAt"]; ok {
+		t.Errorf("OnPatch() kept the created fields: %v", model)
+	}
+}
+
+func TestAuditUserOfDatabase(t *testing.T) {
+	db := openDialect(PostgresDialect{})
+	SetAuditUser(db, "userId")
+	w := NewSqlWriter(db, "items")
+	if w.Audit == nil || w.Audit.User != "userId" {
+		t.Errorf("NewSqlWriter().Audit = %+v, want the user key userId", w.Audit)
+	}
+	Close(db)
+	if user := GetAuditUser(db); user != "" {
+		t.Errorf("GetAuditUser() = %q after Close, want empty", user)
+	}
+}
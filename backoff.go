@@ -0,0 +1,94 @@
+package sql
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+type Backoff interface {
+	// NextDelay returns the delay before the next attempt, given the number of failed attempts and the time elapsed since the first one.
+	// It returns false when there must be no more attempt.
+	NextDelay(failures int, elapsed time.Duration) (time.Duration, bool)
+}
+
+// FixedBackoff waits Delays[i] after the failure i+1, and stops after len(Delays) retries.
+type FixedBackoff struct {
+	Delays []time.Duration
+}
+
+func NewFixedBackoff(delays ...time.Duration) *FixedBackoff {
+	return &FixedBackoff{Delays: delays}
+}
+func (b *FixedBackoff) NextDelay(failures int, elapsed time.Duration) (time.Duration, bool) {
+	if failures > len(b.Delays) {
+		return 0, false
+	}
+	return b.Delays[failures-1], true
+}
+
+// ExponentialBackoff waits InitialInterval * Multiplier^(failures-1), up to MaxInterval.
+// If Jitter is between 0 and 1, the delay is randomized in [delay*(1-Jitter), delay*(1+Jitter)].
+// MaxAttempts counts the first attempt; there is no limit if it is 0.
+type ExponentialBackoff struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	Jitter          float64
+	MaxAttempts     int
+}
+
+func NewExponentialBackoff(initialInterval time.Duration, maxInterval time.Duration, multiplier float64, maxAttempts int) *ExponentialBackoff {
+	return &ExponentialBackoff{InitialInterval: initialInterval, MaxInterval: maxInterval, Multiplier: multiplier, MaxAttempts: maxAttempts}
+}
+func NewJitterBackoff(initialInterval time.Duration, maxInterval time.Duration, multiplier float64, jitter float64, maxAttempts int) *ExponentialBackoff {
+	return &ExponentialBackoff{InitialInterval: initialInterval, MaxInterval: maxInterval, Multiplier: multiplier, Jitter: jitter, MaxAttempts: maxAttempts}
+}
+func (b *ExponentialBackoff) NextDelay(failures int, elapsed time.Duration) (time.Duration, bool) {
+	if b.MaxAttempts > 0 && failures >= b.MaxAttempts {
+		return 0, false
+	}
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	d := float64(b.InitialInterval) * math.Pow(multiplier, float64(failures-1))
+	if b.MaxInterval > 0 && d > float64(b.MaxInterval) {
+		d = float64(b.MaxInterval)
+	}
+	if b.Jitter > 0 {
+		j := math.Min(b.Jitter, 1)
+		d = d * (1 - j + 2*j*rand.Float64())
+	}
+	return time.Duration(d), true
+}
+
+// MaxElapsedBackoff stops the wrapped Backoff when the next attempt would start after MaxElapsedTime.
+type MaxElapsedBackoff struct {
+	Backoff        Backoff
+	MaxElapsedTime time.Duration
+}
+
+func WithMaxElapsedTime(b Backoff, maxElapsedTime time.Duration) *MaxElapsedBackoff {
+	return &MaxElapsedBackoff{Backoff: b, MaxElapsedTime: maxElapsedTime}
+}
+func (b *MaxElapsedBackoff) NextDelay(failures int, elapsed time.Duration) (time.Duration, bool) {
+	d, ok := b.Backoff.NextDelay(failures, elapsed)
+	if !ok || elapsed+d > b.MaxElapsedTime {
+		return 0, false
+	}
+	return d, true
+}
+
+// NewBackoff builds an exponential backoff from c, limited by c.MaxElapsedTime if it is set.
+func NewBackoff(c BackoffConfig) Backoff {
+	initial := time.Duration(c.InitialInterval) * time.Millisecond
+	if initial <= 0 {
+		initial = 50 * time.Millisecond
+	}
+	var b Backoff = NewJitterBackoff(initial, time.Duration(c.MaxInterval)*time.Millisecond, c.Multiplier, c.Jitter, c.MaxAttempts)
+	if c.MaxElapsedTime > 0 {
+		b = WithMaxElapsedTime(b, time.Duration(c.MaxElapsedTime)*time.Millisecond)
+	}
+	return b
+}
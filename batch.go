@@ -12,7 +12,15 @@ func ExecuteStatements(ctx context.Context, db *sql.DB, sts []Statement) (int64,
 	return ExecuteBatch(ctx, db, sts, true, false)
 }
 func ExecuteAll(ctx context.Context, db *sql.DB, stmts []Statement) (int64, error) {
-	tx, er1 := db.Begin()
+	var count int64
+	err := RetryTx(ctx, db, func() (err error) {
+		count, err = executeAll(ctx, db, stmts)
+		return err
+	})
+	return count, err
+}
+func executeAll(ctx context.Context, db *sql.DB, stmts []Statement) (int64, error) {
+	tx, er1 := Begin(ctx, db)
 	if er1 != nil {
 		return 0, er1
 	}
@@ -25,7 +33,7 @@ func ExecuteAll(ctx context.Context, db *sql.DB, stmts []Statement) (int64, erro
 			if er4 != nil {
 				return count, er4
 			}
-			return count, er3
+			return count, HandleError(db, er3)
 		}
 		a2, er5 := r2.RowsAffected()
 		if er5 != nil {
@@ -35,34 +43,28 @@ func ExecuteAll(ctx context.Context, db *sql.DB, stmts []Statement) (int64, erro
 		count = count + a2
 	}
 	er6 := tx.Commit()
-	return count, er6
+	return count, HandleError(db, er6)
 }
 func ExecuteBatch(ctx context.Context, db *sql.DB, sts []Statement, firstRowSuccess bool, countAll bool) (int64, error) {
+	var count int64
+	err := RetryTx(ctx, db, func() (err error) {
+		count, err = executeBatch(ctx, db, sts, firstRowSuccess, countAll)
+		return err
+	})
+	return count, err
+}
+func executeBatch(ctx context.Context, db *sql.DB, sts []Statement, firstRowSuccess bool, countAll bool) (int64, error) {
 	if sts == nil || len(sts) == 0 {
 		return 0, nil
 	}
-	driver := GetDriver(db)
-	tx, er0 := db.Begin()
+	tx, er0 := Begin(ctx, db)
 	if er0 != nil {
 		return 0, er0
 	}
 	result, er1 := tx.ExecContext(ctx, sts[0].Query, sts[0].Args...)
 	if er1 != nil {
 		_ = tx.Rollback()
-		str := er1.Error()
-		if driver == DriverPostgres && strings.Contains(str, "pq: duplicate key value violates unique constraint") {
-			return 0, nil
-		} else if driver == DriverMysql && strings.Contains(str, "Error 1062: Duplicate entry") {
-			return 0, nil //mysql Error 1062: Duplicate entry 'a-1' for key 'PRIMARY'
-		} else if driver == DriverOracle && strings.Contains(str, "ORA-00001: unique constraint") {
-			return 0, nil //mysql Error 1062: Duplicate entry 'a-1' for key 'PRIMARY'
-		} else if driver == DriverMssql && strings.Contains(str, "Violation of PRIMARY KEY constraint") {
-			return 0, nil //Violation of PRIMARY KEY constraint 'PK_aa'. Cannot insert duplicate key in object 'dbo.aa'. The duplicate key value is (b, 2).
-		} else if driver == DriverSqlite3 && strings.Contains(str, "UNIQUE constraint failed") {
-			return 0, nil
-		} else {
-			return 0, er1
-		}
+		return 0, HandleError(db, er1)
 	}
 	rowAffected, er2 := result.RowsAffected()
 	if er2 != nil {
@@ -82,7 +84,7 @@ func ExecuteBatch(ctx context.Context, db *sql.DB, sts []Statement, firstRowSucc
 			if er4 != nil {
 				return count, er4
 			}
-			return count, er3
+			return count, HandleError(db, er3)
 		}
 		a2, er5 := r2.RowsAffected()
 		if er5 != nil {
@@ -93,7 +95,7 @@ func ExecuteBatch(ctx context.Context, db *sql.DB, sts []Statement, firstRowSucc
 	}
 	er6 := tx.Commit()
 	if er6 != nil {
-		return count, er6
+		return count, HandleError(db, er6)
 	}
 	if countAll {
 		return count, nil
@@ -162,7 +164,7 @@ func MakeSchema(modelType reflect.Type) ([]string, []string, map[string]FieldDB)
 		field := modelType.Field(idx)
 		tag, _ := field.Tag.Lookup("gorm")
 		if !strings.Contains(tag, IgnoreReadWrite) {
-			update := !strings.Contains(tag, "update:false")
+			update := !strings.Contains(tag, "update:false") && !isCreatedField(tag)
 			if has := strings.Contains(tag, "column"); has {
 				json := field.Name
 				col := json
@@ -208,7 +210,8 @@ func MakeSchema(modelType reflect.Type) ([]string, []string, map[string]FieldDB)
 	}
 	return columns, keys, schema
 }
-func BuildUpdateBatch(table string, models interface{}, buildParam func(int) string) ([]Statement, error) {
+func BuildUpdateBatch(table string, models interface{}, buildParam func(int) string, options ...Dialect) ([]Statement, error) {
+	d := getDialect(options)
 	s := reflect.Indirect(reflect.ValueOf(models))
 	if s.Kind() != reflect.Slice {
 		return nil, fmt.Errorf("models is not a slice")
@@ -230,7 +233,7 @@ func BuildUpdateBatch(table string, models interface{}, buildParam func(int) str
 		i := 1
 		for _, col := range cols {
 			fdb := schema[col]
-			if !fdb.key && !fdb.Update {
+			if !fdb.key && fdb.Update {
 				f := mv.Field(fdb.index)
 				fieldValue := f.Interface()
 				isNil := false
@@ -242,13 +245,13 @@ func BuildUpdateBatch(table string, models interface{}, buildParam func(int) str
 					}
 				}
 				if isNil {
-					values = append(values, col + "=null")
+					values = append(values, QuoteName(d, col) + "=null")
 				} else {
 					v, ok := GetDBValue(fieldValue)
 					if ok {
-						values = append(values, col + "=" + v)
+						values = append(values, QuoteName(d, col) + "=" + v)
 					} else {
-						values = append(values, col + "=" + buildParam(i))
+						values = append(values, QuoteName(d, col) + "=" + buildParam(i))
 						i = i + 1
 						args = append(args, fieldValue)
 					}
@@ -266,14 +269,14 @@ func BuildUpdateBatch(table string, models interface{}, buildParam func(int) str
 			}
 			v, ok := GetDBValue(fieldValue)
 			if ok {
-				where = append(where, col + "=" + v)
+				where = append(where, QuoteName(d, col) + "=" + v)
 			} else {
-				where = append(where, col + "=" + buildParam(i+1))
+				where = append(where, QuoteName(d, col) + "=" + buildParam(i))
 				i = i + 1
 				args = append(args, fieldValue)
 			}
 		}
-		query := fmt.Sprintf("update %v set %v where %v", table, strings.Join(values, ","), strings.Join(where, ","))
+		query := fmt.Sprintf("update %v set %v where %v", QuoteName(d, table), strings.Join(values, ","), strings.Join(where, " and "))
 		s := Statement{Query: query, Args: args}
 		stmts = append(stmts, s)
 	}
@@ -300,6 +303,8 @@ func BuildInsertBatch(db *sql.DB, table string, models interface{}, options ...f
 	modelType := reflect.TypeOf(first)
 	cols, _, schema := MakeSchema(modelType)
 	driver := GetDriver(db)
+	dialect := GetDialect(db)
+	quotedTable := QuoteName(dialect, table)
 	slen := s.Len()
 	if driver != DriverOracle {
 		for j := 0; j < slen; j++ {
@@ -336,8 +341,8 @@ func BuildInsertBatch(db *sql.DB, table string, models interface{}, options ...f
 			placeholders = append(placeholders, x)
 		}
 		query := fmt.Sprintf(fmt.Sprintf("insert into %s (%s) values %s",
-			table,
-			strings.Join(cols, ","),
+			quotedTable,
+			strings.Join(QuoteNames(dialect, cols), ","),
 			strings.Join(placeholders, ","),
 		))
 		return query, args, nil
@@ -361,7 +366,7 @@ func BuildInsertBatch(db *sql.DB, table string, models interface{}, options ...f
 					}
 				}
 				if !isNil {
-					iCols = append(iCols, col)
+					iCols = append(iCols, QuoteName(dialect, col))
 					v, ok := GetDBValue(fieldValue)
 					if ok {
 						values = append(values, v)
@@ -372,7 +377,7 @@ func BuildInsertBatch(db *sql.DB, table string, models interface{}, options ...f
 					}
 				}
 			}
-			x := fmt.Sprintf("into %s(%s)values(%s)", table, strings.Join(iCols, ","), strings.Join(values, ","))
+			x := fmt.Sprintf("into %s(%s)values(%s)", quotedTable, strings.Join(iCols, ","), strings.Join(values, ","))
 			placeholders = append(placeholders, x)
 		}
 		query := fmt.Sprintf("insert all %s select * from dual", strings.Join(placeholders, " "))
@@ -393,8 +398,15 @@ func BuildSaveBatch(db *sql.DB, table string, models interface{}) ([]Statement,
 	cols, keys, schema := MakeSchema(modelType)
 	slen := s.Len()
 	stmts := make([]Statement, 0)
-	driver := GetDriver(db)
-	if driver == DriverPostgres || driver == DriverMysql {
+	dialect := GetDialect(db)
+	quotedTable := QuoteName(dialect, table)
+	upsert := dialect.Upsert()
+	tenant := FindTenant(modelType)
+	if tenant != nil && (upsert == UpsertStatement || upsert == UpsertInsertOrReplace) {
+		// the update of the conflict must be scoped by the tenant, and insert or replace deletes the row of another tenant
+		upsert = UpsertOnConflict
+	}
+	if upsert == UpsertOnConflict || upsert == UpsertOnDuplicateKey || upsert == UpsertStatement {
 		for j := 0; j < slen; j++ {
 			model := s.Index(j).Interface()
 			mv := reflect.ValueOf(model)
@@ -415,8 +427,11 @@ func BuildSaveBatch(db *sql.DB, table string, models interface{}) ([]Statement,
 						fieldValue = reflect.Indirect(reflect.ValueOf(fieldValue)).Interface()
 					}
 				}
-				if !isNil {
-					iCols = append(iCols, col)
+				if isNil && upsert == UpsertStatement {
+					iCols = append(iCols, QuoteName(dialect, col))
+					values = append(values, "null")
+				} else if !isNil {
+					iCols = append(iCols, QuoteName(dialect, col))
 					v, ok := GetDBValue(fieldValue)
 					if ok {
 						values = append(values, v)
@@ -429,7 +444,7 @@ func BuildSaveBatch(db *sql.DB, table string, models interface{}) ([]Statement,
 			}
 			for _, col := range cols {
 				fdb := schema[col]
-				if !fdb.key && !fdb.Update {
+				if !fdb.key && fdb.Update && tenant.upsertColumn(col) {
 					f := mv.Field(fdb.index)
 					fieldValue := f.Interface()
 					isNil := false
@@ -440,32 +455,42 @@ func BuildSaveBatch(db *sql.DB, table string, models interface{}) ([]Statement,
 							fieldValue = reflect.Indirect(reflect.ValueOf(fieldValue)).Interface()
 						}
 					}
+					var value string
 					if isNil {
-						setColumns = append(setColumns, col + "=null")
+						value = "null"
 					} else {
 						v, ok := GetDBValue(fieldValue)
 						if ok {
-							setColumns = append(setColumns, col + "=" + v)
+							value = v
 						} else {
-							setColumns = append(setColumns, col + "=" + buildParam(i))
+							value = buildParam(i)
 							i = i + 1
 							args = append(args, fieldValue)
 						}
 					}
+					if upsert == UpsertOnDuplicateKey {
+						value = tenant.ifSameTenant(dialect, col, value)
+					}
+					setColumns = append(setColumns, QuoteName(dialect, col) + "=" + value)
 				}
 			}
 			var query string
-			if driver == DriverPostgres {
+			if upsert == UpsertStatement {
+				query = fmt.Sprintf("upsert into %s(%s) values (%s)", quotedTable, strings.Join(iCols, ","), strings.Join(values, ","))
+			} else if upsert == UpsertOnConflict {
 				query = fmt.Sprintf("insert into %s(%s) values (%s) on conflict (%s) do update set %s",
-					table,
+					quotedTable,
 					strings.Join(iCols, ","),
 					strings.Join(values, ","),
-					strings.Join(keys, ","),
+					strings.Join(QuoteNames(dialect, keys), ","),
 					strings.Join(setColumns, ","),
 				)
+				if tenant != nil {
+					query = query + " where " + tenant.excluded(dialect, table)
+				}
 			} else {
 				query = fmt.Sprintf("insert into %s(%s) values (%s) on duplicate key update %s",
-					table,
+					quotedTable,
 					strings.Join(iCols, ","),
 					strings.Join(values, ","),
 					strings.Join(setColumns, ","),
@@ -474,7 +499,7 @@ func BuildSaveBatch(db *sql.DB, table string, models interface{}) ([]Statement,
 			s := Statement{Query: query, Args: args}
 			stmts = append(stmts, s)
 		}
-	} else if driver == DriverSqlite3 {
+	} else if upsert == UpsertInsertOrReplace {
 		for j := 0; j < slen; j++ {
 			model := s.Index(j).Interface()
 			mv := reflect.ValueOf(model)
@@ -494,7 +519,7 @@ func BuildSaveBatch(db *sql.DB, table string, models interface{}) ([]Statement,
 						fieldValue = reflect.Indirect(reflect.ValueOf(fieldValue)).Interface()
 					}
 				}
-				iCols = append(iCols, col)
+				iCols = append(iCols, QuoteName(dialect, col))
 				if isNil {
 					values = append(values, "null")
 				} else {
@@ -508,11 +533,11 @@ func BuildSaveBatch(db *sql.DB, table string, models interface{}) ([]Statement,
 					}
 				}
 			}
-			query := fmt.Sprintf("insert or replace into %s(%s) values (%s)", table, strings.Join(iCols, ","), strings.Join(values, ","))
+			query := fmt.Sprintf("insert or replace into %s(%s) values (%s)", quotedTable, strings.Join(iCols, ","), strings.Join(values, ","))
 			s := Statement{Query: query, Args: args}
 			stmts = append(stmts, s)
 		}
-	} else if driver == DriverOracle {
+	} else if upsert == UpsertMergeDual {
 		for j := 0; j < slen; j++ {
 			model := s.Index(j).Interface()
 			uniqueCols := make([]string, 0)
@@ -528,25 +553,28 @@ func BuildSaveBatch(db *sql.DB, table string, models interface{}) ([]Statement,
 			}
 			for v, key := range sorted {
 				values = append(values, attrs[key])
-				tkey := `"` + strings.Replace(key, `"`, `""`, -1) + `"`
-				tkey = strings.ToUpper(tkey)
-				setColumns = append(setColumns, "a."+tkey+" = temp."+tkey)
-				inColumns = append(inColumns, "temp."+key)
+				tkey := QuoteName(dialect, key)
+				if tenant.upsertColumn(key) {
+					setColumns = append(setColumns, "a."+tkey+" = temp."+tkey)
+				}
+				inColumns = append(inColumns, "temp."+tkey)
 				variables = append(variables, fmt.Sprintf(":%d "+tkey, v))
 				insertCols = append(insertCols, tkey)
 			}
 			for key, val := range unique {
-				tkey := `"` + strings.Replace(key, `"`, `""`, -1) + `"`
-				tkey = strings.ToUpper(tkey)
+				tkey := QuoteName(dialect, key)
 				onDupe := "a." + tkey + " = " + "temp." + tkey
 				uniqueCols = append(uniqueCols, onDupe)
 				variables = append(variables, fmt.Sprintf(":%s "+tkey, key))
-				inColumns = append(inColumns, "temp."+key)
+				inColumns = append(inColumns, "temp."+tkey)
 				values = append(values, val)
 				insertCols = append(insertCols, tkey)
 			}
+			if tenant != nil {
+				uniqueCols = append(uniqueCols, tenant.matched(dialect, "a", "temp"))
+			}
 			query := fmt.Sprintf("MERGE INTO %s a USING (SELECT %s FROM dual) temp ON  (%s) WHEN MATCHED THEN UPDATE SET %s WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)",
-				`"`+strings.Replace(table, `"`, `""`, -1)+`"`,
+				quotedTable,
 				strings.Join(variables, ", "),
 				strings.Join(uniqueCols, " AND "),
 				strings.Join(setColumns, ", "),
@@ -556,7 +584,7 @@ func BuildSaveBatch(db *sql.DB, table string, models interface{}) ([]Statement,
 			s := Statement{Query: query, Args: values}
 			stmts = append(stmts, s)
 		}
-	} else if driver == DriverMssql {
+	} else if upsert == UpsertMerge {
 		for j := 0; j < slen; j++ {
 			model := s.Index(j).Interface()
 			uniqueCols := make([]string, 0)
@@ -571,22 +599,27 @@ func BuildSaveBatch(db *sql.DB, table string, models interface{}) ([]Statement,
 			}
 			for _, key := range sorted {
 				//mainScope.AddToVars(attrs[key])
-				tkey := `"` + strings.Replace(key, `"`, `""`, -1) + `"`
+				tkey := QuoteName(dialect, key)
 				dbColumns = append(dbColumns, tkey)
 				variables = append(variables, "?")
 				values = append(values, attrs[key])
-				setColumns = append(setColumns, tkey+" = temp."+tkey)
+				if tenant.upsertColumn(key) {
+					setColumns = append(setColumns, tkey+" = temp."+tkey)
+				}
 			}
 			for i, val := range unique {
-				tkey := `"` + strings.Replace(i, `"`, `""`, -1) + `"`
-				dbColumns = append(dbColumns, `"`+strings.Replace(tkey, `"`, `""`, -1)+`"`)
+				tkey := QuoteName(dialect, i)
+				dbColumns = append(dbColumns, tkey)
 				variables = append(variables, "?")
 				values = append(values, val)
-				onDupe := table + "." + tkey + " = " + "temp." + tkey
+				onDupe := quotedTable + "." + tkey + " = " + "temp." + tkey
 				uniqueCols = append(uniqueCols, onDupe)
 			}
+			if tenant != nil {
+				uniqueCols = append(uniqueCols, tenant.matched(dialect, quotedTable, "temp"))
+			}
 			query := fmt.Sprintf("MERGE INTO %s USING (VALUES %s) AS temp (%s) ON %s WHEN MATCHED THEN UPDATE SET %s WHEN NOT MATCHED THEN INSERT (%s) VALUES %s;",
-				`"`+strings.Replace(table, `"`, `""`, -1)+`"`,
+				quotedTable,
 				strings.Join(variables, ", "),
 				strings.Join(dbColumns, ", "),
 				strings.Join(uniqueCols, " AND "),
@@ -602,13 +635,16 @@ func BuildSaveBatch(db *sql.DB, table string, models interface{}) ([]Statement,
 }
 
 func InsertMany(ctx context.Context, db *sql.DB, tableName string, models interface{}, options ...func(int) string) (int64, error) {
+	if er0 := SetTenant(ctx, models); er0 != nil {
+		return 0, er0
+	}
 	query, args, er1 := BuildInsertBatch(db, tableName, models, options...)
 	if er1 != nil {
 		return 0, er1
 	}
-	x, er2 := db.ExecContext(ctx, query, args...)
+	x, er2 := GetExecutor(ctx, db).ExecContext(ctx, query, args...)
 	if er2 != nil {
-		return 0, er2
+		return 0, HandleError(db, er2)
 	}
 	return x.RowsAffected()
 }
@@ -619,13 +655,27 @@ func UpdateMany(ctx context.Context, db *sql.DB, tableName string, models interf
 	} else {
 		buildParam = GetBuild(db)
 	}
-	stmts, er1 := BuildUpdateBatch(tableName, models, buildParam)
+	if er0 := SetTenant(ctx, models); er0 != nil {
+		return 0, er0
+	}
+	dialect := GetDialect(db)
+	stmts, er1 := BuildUpdateBatch(tableName, models, buildParam, dialect)
 	if er1 != nil {
 		return 0, er1
 	}
+	t, tenant, er2 := getTenant(ctx, reflect.Indirect(reflect.ValueOf(models)).Type().Elem())
+	if er2 != nil {
+		return 0, er2
+	}
+	for i := range stmts {
+		stmts[i].Query, stmts[i].Args = t.Where(dialect, stmts[i].Query, stmts[i].Args, tenant, buildParam)
+	}
 	return ExecuteAll(ctx, db, stmts)
 }
 func SaveMany(ctx context.Context, db *sql.DB, tableName string, models interface{}) (int64, error) {
+	if er0 := SetTenant(ctx, models); er0 != nil {
+		return 0, er0
+	}
 	stmts, er1 := BuildSaveBatch(db, tableName, models)
 	if er1 != nil {
 		return 0, er1
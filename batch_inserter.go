@@ -11,6 +11,7 @@ type BatchInserter struct {
 	tableName  string
 	BuildParam func(i int) string
 	Map        func(ctx context.Context, model interface{}) (interface{}, error)
+	Audit      *Audit
 }
 func NewBatchInserter(db *sql.DB, tableName string, options...func(context.Context, interface{}) (interface{}, error)) *BatchInserter {
 	var mp func(context.Context, interface{}) (interface{}, error)
@@ -26,10 +27,11 @@ func NewSqlBatchInserter(db *sql.DB, tableName string, mp func(context.Context,
 	} else {
 		buildParam = GetBuild(db)
 	}
-	return &BatchInserter{db: db, tableName: tableName, BuildParam: buildParam, Map: mp}
+	return &BatchInserter{db: db, tableName: tableName, BuildParam: buildParam, Map: mp, Audit: NewAudit(GetAuditUser(db))}
 }
 
 func (w *BatchInserter) Write(ctx context.Context, models interface{}) ([]int, []int, error) {
+	w.Audit.OnInsert(ctx, models)
 	successIndices := make([]int, 0)
 	failIndices := make([]int, 0)
 	var models2 interface{}
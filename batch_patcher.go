@@ -15,6 +15,8 @@ type BatchPatcher struct {
 	buildParam  func(i int) string
 	modelsType  reflect.Type
 	modelsTypes reflect.Type
+	tenant      *Tenant
+	Audit       *Audit
 }
 
 func NewBatchPatcher(db *sql.DB, tableName string, modelType reflect.Type, options...func(i int) string) *BatchPatcher {
@@ -32,13 +34,29 @@ func NewBatchPatcherWithIds(db *sql.DB, tableName string, modelType reflect.Type
 	} else {
 		buildParam = GetBuild(db)
 	}
-	return &BatchPatcher{db: db, tableName: tableName, idNames: fieldName, idJsonName: idJsonName, modelsType: modelType, modelsTypes: modelsTypes, buildParam: buildParam}
+	return &BatchPatcher{db: db, tableName: tableName, idNames: fieldName, idJsonName: idJsonName, modelsType: modelType, modelsTypes: modelsTypes, buildParam: buildParam, tenant: FindTenant(modelType), Audit: NewAudit(GetAuditUser(db))}
 }
 
 func (w *BatchPatcher) Write(ctx context.Context, models []map[string]interface{}) ([]int, []int, error) {
 	successIndices := make([]int, 0)
 	failIndices := make([]int, 0)
-	_, err := PatchInTransaction(ctx, w.db, w.tableName, models, w.idNames, w.idJsonName, w.buildParam)
+	for _, model := range models {
+		w.Audit.OnPatchColumns(ctx, model, w.modelsType)
+	}
+	// the models are keyed by the columns, and the tenant column is a key, so it is in the condition, not in the set
+	keys := w.idNames
+	if w.tenant != nil {
+		tenant, er0 := w.tenant.Value(ctx)
+		if er0 != nil {
+			failIndices = toArrayMapIndex(models, failIndices)
+			return successIndices, failIndices, er0
+		}
+		for _, model := range models {
+			model[w.tenant.Column] = tenant
+		}
+		keys = append(append(make([]string, 0, len(keys)+1), keys...), w.tenant.Column)
+	}
+	_, err := PatchInTransaction(ctx, w.db, w.tableName, models, keys, keys, w.buildParam)
 
 	if err == nil {
 		// Return full success
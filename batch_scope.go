@@ -22,7 +22,7 @@ type BatchStatement struct {
 	AttributeKeys map[string]interface{}
 }
 
-func newStatement(value interface{}, excludeColumns ...string) BatchStatement {
+func newStatement(d Dialect, value interface{}, excludeColumns ...string) BatchStatement {
 	attribute, attributeKey, _, _ := ExtractMapValue(value, &excludeColumns, false)
 	attrSize := len(attribute)
 	modelType := reflect.TypeOf(value)
@@ -30,7 +30,7 @@ func newStatement(value interface{}, excludeColumns ...string) BatchStatement {
 	// Replace with database column name
 	dbColumns := make([]string, 0, attrSize)
 	for _, key := range SortedKeys(attribute) {
-		dbColumns = append(dbColumns, QuoteColumnName(key))
+		dbColumns = append(dbColumns, QuoteName(d, key))
 	}
 	// Scope to eventually run SQL
 	statement := BatchStatement{Keys: keys, Columns: dbColumns, Attributes: attribute, AttributeKeys: attributeKey}
@@ -154,6 +154,8 @@ func InsertInTransactionTx(ctx context.Context, db *sql.DB, tx *sql.Tx, tableNam
 			return 0, nil
 		}
 		driver := GetDriver(db)
+		dialect := GetDialect(db)
+		table := QuoteName(dialect, tableName)
 		firstObj := objectValues.Index(0).Interface()
 		columns, primaryKeys, _, err := ExtractMapValue(firstObj, &excludeColumns, true)
 		if err != nil {
@@ -169,7 +171,7 @@ func InsertInTransactionTx(ctx context.Context, db *sql.DB, tx *sql.Tx, tableNam
 		// Replace with database column name
 		dbColumns := make([]string, 0, attrSize)
 		for _, key := range SortedKeys(columns) {
-			dbColumns = append(dbColumns, QuoteColumnName(key))
+			dbColumns = append(dbColumns, QuoteName(dialect, key))
 		}
 		var start int
 		for i := 0; i < objectValues.Len(); i++ {
@@ -203,24 +205,24 @@ func InsertInTransactionTx(ctx context.Context, db *sql.DB, tx *sql.Tx, tableNam
 			if skipDuplicate {
 				if driver == DriverPostgres {
 					query = fmt.Sprintf("insert into %s (%s) values %s on conflict do nothing",
-						tableName,
+						table,
 						strings.Join(dbColumns, ", "),
 						strings.Join(placeholders, ", "),
 					)
 				} else if driver == DriverSqlite3 {
 					query = fmt.Sprintf("insert or ignore into %s (%s) values %s",
-						tableName,
+						table,
 						strings.Join(dbColumns, ", "),
 						strings.Join(placeholders, ", "),
 					)
 				} else if driver == DriverOracle || driver == DriverMysql {
 					var qKey []string
 					for _, i2 := range pkey {
-						key := i2 + " = " + i2
+						key := QuoteName(dialect, i2) + " = " + QuoteName(dialect, i2)
 						qKey = append(qKey, key)
 					}
 					query = fmt.Sprintf("insert into %s (%s) values %s on duplicate key update %s",
-						tableName,
+						table,
 						strings.Join(dbColumns, ", "),
 						strings.Join(placeholders, ", "),
 						strings.Join(qKey, ", "),
@@ -230,7 +232,7 @@ func InsertInTransactionTx(ctx context.Context, db *sql.DB, tx *sql.Tx, tableNam
 				}
 			} else {
 				query = fmt.Sprintf("insert into %s (%s) values %s",
-					tableName,
+					table,
 					strings.Join(dbColumns, ", "),
 					strings.Join(placeholders, ", "),
 				)
@@ -238,7 +240,7 @@ func InsertInTransactionTx(ctx context.Context, db *sql.DB, tx *sql.Tx, tableNam
 			_, execErr := tx.ExecContext(ctx, query, scope.Values...)
 			if execErr != nil {
 				_ = tx.Rollback()
-				return 0, execErr
+				return 0, HandleError(db, execErr)
 			}
 		}
 		count := objectValues.Len()
@@ -284,6 +286,8 @@ func InsertManyRaw(ctx context.Context, db *sql.DB, tableName string, objects []
 		return 0, nil
 	}
 	driver := GetDriver(db)
+	dialect := GetDialect(db)
+	table := QuoteName(dialect, tableName)
 	firstAttrs, primaryKeys, _, err := ExtractMapValue(objects[0], &excludeColumns, true)
 	if err != nil {
 		return 0, err
@@ -304,7 +308,7 @@ func InsertManyRaw(ctx context.Context, db *sql.DB, tableName string, objects []
 	// Replace with database column name
 	dbColumns := make([]string, 0, attrSize)
 	for _, key := range SortedKeys(firstAttrs) {
-		dbColumns = append(dbColumns, QuoteColumnName(key))
+		dbColumns = append(dbColumns, QuoteName(dialect, key))
 	}
 	var start int
 	for _, obj := range objects {
@@ -341,24 +345,24 @@ func InsertManyRaw(ctx context.Context, db *sql.DB, tableName string, objects []
 	if skipDuplicate {
 		if driver == DriverPostgres {
 			query = fmt.Sprintf("insert into %s (%s) values %s on conflict do nothing",
-				tableName,
+				table,
 				strings.Join(dbColumns, ", "),
 				strings.Join(placeholders, ", "),
 			)
 		} else if driver == DriverSqlite3 {
 			query = fmt.Sprintf("insert or ignore into %s (%s) values %s",
-				tableName,
+				table,
 				strings.Join(dbColumns, ", "),
 				strings.Join(placeholders, ", "),
 			)
 		} else if driver == DriverOracle || driver == DriverMysql {
 			var qKey []string
 			for _, i2 := range pkey {
-				key := i2 + " = " + i2
+				key := QuoteName(dialect, i2) + " = " + QuoteName(dialect, i2)
 				qKey = append(qKey, key)
 			}
 			query = fmt.Sprintf("insert into %s (%s) values %s on duplicate key update %s",
-				tableName,
+				table,
 				strings.Join(dbColumns, ", "),
 				strings.Join(placeholders, ", "),
 				strings.Join(qKey, ", "),
@@ -369,7 +373,7 @@ func InsertManyRaw(ctx context.Context, db *sql.DB, tableName string, objects []
 	} else {
 		if driver != DriverOracle {
 			query = fmt.Sprintf(fmt.Sprintf("insert into %s (%s) values %s",
-				tableName,
+				table,
 				strings.Join(dbColumns, ","),
 				strings.Join(placeholders, ","),
 			))
@@ -377,7 +381,7 @@ func InsertManyRaw(ctx context.Context, db *sql.DB, tableName string, objects []
 			all := make([]string, 0)
 			colNames := "(" + strings.Join(dbColumns, ",") + ")"
 			for _, s0 := range placeholders {
-				s1 := fmt.Sprintf(" into %s %s values %s ", tableName, colNames, s0)
+				s1 := fmt.Sprintf(" into %s %s values %s ", table, colNames, s0)
 				all = append(all, s1)
 			}
 			query = fmt.Sprintf(" insert all %s select * from dual", strings.Join(all, " "))
@@ -385,18 +389,28 @@ func InsertManyRaw(ctx context.Context, db *sql.DB, tableName string, objects []
 	}
 	mainScope.Query = query
 
-	x, err := db.ExecContext(ctx, mainScope.Query, mainScope.Values...)
+	x, err := GetExecutor(ctx, db).ExecContext(ctx, mainScope.Query, mainScope.Values...)
 	if err != nil {
-		return -1, err
+		return -1, HandleError(db, err)
 	}
 	return x.RowsAffected()
 }
 
 func InsertInTransaction(ctx context.Context, db *sql.DB, tableName string, objects []interface{}, skipDuplicate bool, buildParam func(i int) string, excludeColumns ...string) (int64, error) {
+	var count int64
+	err := RetryTx(ctx, db, func() (err error) {
+		count, err = insertInTransaction(ctx, db, tableName, objects, skipDuplicate, buildParam, excludeColumns...)
+		return err
+	})
+	return count, err
+}
+func insertInTransaction(ctx context.Context, db *sql.DB, tableName string, objects []interface{}, skipDuplicate bool, buildParam func(i int) string, excludeColumns ...string) (int64, error) {
 	if len(objects) == 0 {
 		return 0, nil
 	}
 	driver := GetDriver(db)
+	dialect := GetDialect(db)
+	table := QuoteName(dialect, tableName)
 	firstAttrs, _, _, err := ExtractMapValue(objects[0], &excludeColumns, true)
 	if err != nil {
 		return 0, err
@@ -408,10 +422,10 @@ func InsertInTransaction(ctx context.Context, db *sql.DB, tableName string, obje
 	// Replace with database column name
 	dbColumns := make([]string, 0, attrSize)
 	for _, key := range SortedKeys(firstAttrs) {
-		dbColumns = append(dbColumns, QuoteColumnName(key))
+		dbColumns = append(dbColumns, QuoteName(dialect, key))
 	}
 
-	tx, err := db.Begin()
+	tx, err := Begin(ctx, db)
 	if err != nil {
 		return 0, err
 	}
@@ -423,11 +437,13 @@ func InsertInTransaction(ctx context.Context, db *sql.DB, tableName string, obje
 		placeholders := make([]string, 0, attrSize)
 		objAttrs, _, _, err := ExtractMapValue(obj, &excludeColumns, true)
 		if err != nil {
+			_ = tx.Rollback()
 			return 0, err
 		}
 
 		// If object sizes are different, SQL statement loses consistency
 		if len(objAttrs) != attrSize {
+			_ = tx.Rollback()
 			return 0, errors.New("attribute sizes are inconsistent")
 		}
 
@@ -451,34 +467,35 @@ func InsertInTransaction(ctx context.Context, db *sql.DB, tableName string, obje
 		if skipDuplicate {
 			if driver == DriverPostgres {
 				query = fmt.Sprintf("insert into %s (%s) values %s on conflict do nothing",
-					tableName,
+					table,
 					strings.Join(dbColumns, ", "),
 					strings.Join(placeholders, ", "),
 				)
 			} else if driver == DriverSqlite3 {
 				query = fmt.Sprintf("insert or ignore into %s (%s) values %s",
-					tableName,
+					table,
 					strings.Join(dbColumns, ", "),
 					strings.Join(placeholders, ", "),
 				)
 			} else if driver == DriverOracle || driver == DriverMysql {
 				var qKey []string
 				for _, i2 := range pkey {
-					key := i2 + " = " + i2
+					key := QuoteName(dialect, i2) + " = " + QuoteName(dialect, i2)
 					qKey = append(qKey, key)
 				}
 				query = fmt.Sprintf("insert into %s (%s) values %s on duplicate key update %s",
-					tableName,
+					table,
 					strings.Join(dbColumns, ", "),
 					strings.Join(placeholders, ", "),
 					strings.Join(qKey, ", "),
 				)
 			} else {
+				_ = tx.Rollback()
 				return 0, fmt.Errorf("only support skip duplicate on mysql and postgresql, current vendor is %s", driver)
 			}
 		} else {
 			query = fmt.Sprintf("insert into %s (%s) values %s",
-				tableName,
+				table,
 				strings.Join(dbColumns, ", "),
 				strings.Join(placeholders, ", "),
 			)
@@ -489,12 +506,12 @@ func InsertInTransaction(ctx context.Context, db *sql.DB, tableName string, obje
 		_, execErr := tx.ExecContext(ctx, mainScope.Query, mainScope.Values...)
 		if execErr != nil {
 			_ = tx.Rollback()
-			return 0, execErr
+			return 0, HandleError(db, execErr)
 		}
 	}
 	err = tx.Commit()
 	if err != nil {
-		return 0, err
+		return 0, HandleError(db, err)
 	}
 	count := len(objects)
 	return int64(count), err
@@ -514,8 +531,17 @@ func InterfaceSlice(slice interface{}) ([]interface{}, error) {
 }
 
 func UpdateInTransaction(ctx context.Context, db *sql.DB, tableName string, objects []interface{}, options...func(i int) string) (int64, error) {
+	var count int64
+	err := RetryTx(ctx, db, func() (err error) {
+		count, err = updateInTransaction(ctx, db, tableName, objects, options...)
+		return err
+	})
+	return count, err
+}
+func updateInTransaction(ctx context.Context, db *sql.DB, tableName string, objects []interface{}, options...func(i int) string) (int64, error) {
 	var placeholder []string
-	driver := GetDriver(db)
+	dialect := GetDialect(db)
+	table := QuoteName(dialect, tableName)
 	var buildParam func(i int) string
 	if len(options) > 0 && options[0] != nil {
 		buildParam = options[0]
@@ -528,13 +554,13 @@ func UpdateInTransaction(ctx context.Context, db *sql.DB, tableName string, obje
 		return 0, nil
 	}
 	valueDefault := objects[0]
-	statement := newStatement(valueDefault, placeholder...)
+	statement := newStatement(dialect, valueDefault, placeholder...)
 	columns := make([]string, 0) // columns set value for update
 	for _, key := range SortedKeys(statement.Attributes) {
-		columns = append(columns, QuoteByDriver(key, driver))
+		columns = append(columns, QuoteName(dialect, key))
 	}
 	for _, obj := range objects {
-		scope := newStatement(obj, placeholder...)
+		scope := newStatement(dialect, obj, placeholder...)
 		// Append variables set column
 		for _, key := range SortedKeys(scope.Attributes) {
 			scope.Values = append(scope.Values, scope.Attributes[key])
@@ -552,19 +578,19 @@ func UpdateInTransaction(ctx context.Context, db *sql.DB, tableName string, obje
 			return 0, err1
 		}
 		numKeys := len(scope.Keys)
-		where, whereVal, err2 := BuildSqlParametersAndValues(scope.Keys, scope.Values, &numKeys, n, " and ", buildParam)
+		where, whereVal, err2 := BuildSqlParametersAndValues(QuoteNames(dialect, scope.Keys), scope.Values, &numKeys, n, " and ", buildParam)
 		if err2 != nil {
 			return 0, err2
 		}
 		setVal = append(setVal, whereVal...)
 		value = append(value, setVal)
 		query = append(query, fmt.Sprintf(fmt.Sprintf("update %s set %s where %s",
-			tableName,
+			table,
 			sets,
 			where,
 		)))
 	}
-	tx, err := db.Begin()
+	tx, err := Begin(ctx, db)
 	if err != nil {
 		return 0, err
 	}
@@ -573,24 +599,34 @@ func UpdateInTransaction(ctx context.Context, db *sql.DB, tableName string, obje
 		_, execErr := tx.ExecContext(ctx, query[i], value[i]...)
 		if execErr != nil {
 			_ = tx.Rollback()
-			return 0, execErr
+			return 0, HandleError(db, execErr)
 		}
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		return 0, err
+		return 0, HandleError(db, err)
 	}
 	total := int64(len(query))
 	return total, err
 }
 func PatchInTransaction(ctx context.Context, db *sql.DB, tableName string, objects []map[string]interface{}, idTagJsonNames []string, idColumNames []string, options...func(i int) string) (int64, error) {
+	var count int64
+	err := RetryTx(ctx, db, func() (err error) {
+		count, err = patchInTransaction(ctx, db, tableName, objects, idTagJsonNames, idColumNames, options...)
+		return err
+	})
+	return count, err
+}
+func patchInTransaction(ctx context.Context, db *sql.DB, tableName string, objects []map[string]interface{}, idTagJsonNames []string, idColumNames []string, options...func(i int) string) (int64, error) {
 	var buildParam func(i int) string
 	if len(options) > 0 && options[0] != nil {
 		buildParam = options[0]
 	} else {
 		buildParam = GetBuild(db)
 	}
+	dialect := GetDialect(db)
+	table := QuoteName(dialect, tableName)
 	var query []string
 	var value [][]interface{}
 	if len(objects) == 0 {
@@ -612,26 +648,26 @@ func PatchInTransaction(ctx context.Context, db *sql.DB, tableName string, objec
 		}
 
 		n := len(scope.Columns)
-		sets, setVal, err1 := BuildSqlParametersAndValues(scope.Columns, scope.Values, &n, 0, ", ", buildParam)
+		sets, setVal, err1 := BuildSqlParametersAndValues(QuoteNames(dialect, scope.Columns), scope.Values, &n, 0, ", ", buildParam)
 		if err1 != nil {
 			return 0, err1
 		}
-		value = append(value, setVal)
 		numKeys := len(scope.Keys)
-		where, whereVal, err2 := BuildSqlParametersAndValues(scope.Keys, scope.Values, &numKeys, n, " and ", buildParam)
+		where, whereVal, err2 := BuildSqlParametersAndValues(QuoteNames(dialect, scope.Keys), scope.Values, &numKeys, n, " and ", buildParam)
 		if err2 != nil {
 			return 0, err2
 		}
-		value = append(value, whereVal)
+		setVal = append(setVal, whereVal...)
+		value = append(value, setVal)
 		query = append(query, fmt.Sprintf("update %s set %s where %s",
-			tableName,
+			table,
 			sets,
 			where,
 		))
 	}
 
 
-	tx, err := db.Begin()
+	tx, err := Begin(ctx, db)
 	if err != nil {
 		return 0, err
 	}
@@ -640,13 +676,13 @@ func PatchInTransaction(ctx context.Context, db *sql.DB, tableName string, objec
 		_, execErr := tx.ExecContext(ctx, query[i], value[i]...)
 		if execErr != nil {
 			_ = tx.Rollback()
-			return 0, execErr
+			return 0, HandleError(db, execErr)
 		}
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		return 0, err
+		return 0, HandleError(db, err)
 	}
 	total := int64(len(query))
 	return total, err
@@ -658,6 +694,8 @@ func PatchMaps(ctx context.Context, db *sql.DB, tableName string, objects []map[
 	} else {
 		buildParam = GetBuild(db)
 	}
+	dialect := GetDialect(db)
+	table := QuoteName(dialect, tableName)
 	var query []string
 	var value [][]interface{}
 	if len(objects) == 0 {
@@ -679,19 +717,19 @@ func PatchMaps(ctx context.Context, db *sql.DB, tableName string, objects []map[
 		}
 
 		n := len(scope.Columns)
-		sets, setVal, err1 := BuildSqlParametersAndValues(scope.Columns, scope.Values, &n, 0, ", ", buildParam)
+		sets, setVal, err1 := BuildSqlParametersAndValues(QuoteNames(dialect, scope.Columns), scope.Values, &n, 0, ", ", buildParam)
 		if err1 != nil {
 			return 0, err1
 		}
-		value = append(value, setVal)
 		numKeys := len(scope.Keys)
-		where, whereVal, err2 := BuildSqlParametersAndValues(scope.Keys, scope.Values, &numKeys, n, " and ", buildParam)
+		where, whereVal, err2 := BuildSqlParametersAndValues(QuoteNames(dialect, scope.Keys), scope.Values, &numKeys, n, " and ", buildParam)
 		if err2 != nil {
 			return 0, err2
 		}
-		value = append(value, whereVal)
+		setVal = append(setVal, whereVal...)
+		value = append(value, setVal)
 		query = append(query, fmt.Sprintf("update %s set %s where %s",
-			tableName,
+			table,
 			sets,
 			where,
 		))
@@ -699,9 +737,9 @@ func PatchMaps(ctx context.Context, db *sql.DB, tableName string, objects []map[
 
 	var count int64
 	for i := 0; i < len(query); i++ {
-		x, execErr := db.ExecContext(ctx, query[i], value[i]...)
+		x, execErr := GetExecutor(ctx, db).ExecContext(ctx, query[i], value[i]...)
 		if execErr != nil {
-			return 0, execErr
+			return 0, HandleError(db, execErr)
 		}
 		rowsAffected, _ := x.RowsAffected()
 		count += rowsAffected
@@ -759,9 +797,11 @@ func BuildSqlParametersByColumns(columns []string, values []interface{}, n int,
 	return strings.Join(arr, joinStr), nil
 }
 
-func BuildParamWithNull(colName string) string {
-	return QuoteColumnName(colName)+"=null"
+func BuildParamWithNull(colName string, options ...Dialect) string {
+	return QuoteColumnName(colName, options...) + "=null"
 }
+
+// BuildSqlParametersAndValues expects the columns to be quoted already.
 func BuildSqlParametersAndValues(columns []string, values []interface{}, n *int, start int, joinStr string, buildParam func(int) string) (string, []interface{}, error) {
 	arr := make([]string, *n)
 	j := start
@@ -769,16 +809,16 @@ func BuildSqlParametersAndValues(columns []string, values []interface{}, n *int,
 	for i, _ := range arr {
 		columnName := columns[i]
 		if values[j] == nil {
-			arr[i] = BuildParamWithNull(columnName)
-			copy(values[i:], values[i+1:])
+			arr[i] = columnName + "=null"
+			copy(values[j:], values[j+1:])
 			values[len(values)-1] = ""
 			values = values[:len(values)-1]
 			*n--
 		} else {
-			arr[i] = fmt.Sprintf("%s = %s", columnName, BuildParametersFrom(start, 1, buildParam))
+			arr[i] = fmt.Sprintf("%s = %s", columnName, BuildParametersFrom(start+len(valueParams), 1, buildParam))
 			valueParams = append(valueParams, values[j])
+			j++
 		}
-		j++
 	}
 	return strings.Join(arr, joinStr), valueParams, nil
 }
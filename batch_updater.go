@@ -11,6 +11,7 @@ type BatchUpdater struct {
 	tableName  string
 	BuildParam func(i int) string
 	Map        func(ctx context.Context, model interface{}) (interface{}, error)
+	Audit      *Audit
 }
 func NewBatchUpdater(db *sql.DB, tableName string, options...func(context.Context, interface{}) (interface{}, error)) *BatchUpdater {
 	var mp func(context.Context, interface{}) (interface{}, error)
@@ -26,9 +27,10 @@ func NewSqlBatchUpdater(db *sql.DB, tableName string, mp func(context.Context, i
 	} else {
 		buildParam = GetBuild(db)
 	}
-	return &BatchUpdater{db: db, tableName: tableName, Map: mp, BuildParam: buildParam}
+	return &BatchUpdater{db: db, tableName: tableName, Map: mp, BuildParam: buildParam, Audit: NewAudit(GetAuditUser(db))}
 }
 func (w *BatchUpdater) Write(ctx context.Context, models interface{}) ([]int, []int, error) {
+	w.Audit.OnUpdate(ctx, models)
 	successIndices := make([]int, 0)
 	failIndices := make([]int, 0)
 	var models2 interface{}
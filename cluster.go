@@ -0,0 +1,318 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	RoundRobin       = "round_robin"
+	LeastConnections = "least_connections"
+)
+
+type ClusterConfig struct {
+	Primary  Config   `mapstructure:"primary" json:"primary,omitempty" gorm:"column:primary" bson:"primary,omitempty" dynamodbav:"primary,omitempty" firestore:"primary,omitempty"`
+	Replicas []Config `mapstructure:"replicas" json:"replicas,omitempty" gorm:"column:replicas" bson:"replicas,omitempty" dynamodbav:"replicas,omitempty" firestore:"replicas,omitempty"`
+	// Balancer is round_robin (by default) or least_connections
+	Balancer string `mapstructure:"balancer" json:"balancer,omitempty" gorm:"column:balancer" bson:"balancer,omitempty" dynamodbav:"balancer,omitempty" firestore:"balancer,omitempty"`
+	// HealthCheck is the interval in milliseconds to ping the replicas, 10 seconds by default
+	HealthCheck int64 `mapstructure:"health_check" json:"healthCheck,omitempty" gorm:"column:healthcheck" bson:"healthCheck,omitempty" dynamodbav:"healthCheck,omitempty" firestore:"healthCheck,omitempty"`
+}
+
+// Cluster splits the reads and the writes of a primary database: the select statements outside of a transaction, which are pure reads, go to a healthy replica,
+// and the other statements, the statements of a transaction and the reads of a context of WithPrimary go to the primary.
+// The Writer, Loader, Searcher and BuildFromQuery of the primary use the replicas, because GetExecutor uses the cluster of the primary.
+type Cluster struct {
+	Primary  *sql.DB
+	Replicas []*sql.DB
+	Balancer string
+	// IsRead returns true for the statements which can run on a replica; by default, the select statements which do not lock the rows,
+	// and call only the functions which are known to be pure reads, so that a select of nextval or of a function with side effects goes to the primary.
+	IsRead   func(query string) bool
+	down     []int32
+	next     uint32
+	checking int32
+	stop     chan struct{}
+	once     sync.Once
+	// owned is true if the cluster opened the primary, so that Close closes it
+	owned bool
+}
+
+// DefaultHealthCheck is the interval of the health check of the replicas of a cluster.
+const DefaultHealthCheck = 10 * time.Second
+
+// NewCluster creates the cluster of primary and replicas, and registers it, so that the reads of primary go to the replicas.
+// It checks the health of the replicas every DefaultHealthCheck until Close, so that a replica which is down is back in the balancing when it is up again.
+// The primary stays open after Close, because it is opened by the caller.
+func NewCluster(primary *sql.DB, replicas []*sql.DB, options ...string) *Cluster {
+	balancer := RoundRobin
+	if len(options) > 0 && len(options[0]) > 0 {
+		balancer = options[0]
+	}
+	return newCluster(primary, replicas, balancer, DefaultHealthCheck)
+}
+func newCluster(primary *sql.DB, replicas []*sql.DB, balancer string, interval time.Duration) *Cluster {
+	c := &Cluster{Primary: primary, Replicas: replicas, Balancer: balancer, IsRead: isRead, down: make([]int32, len(replicas)), stop: make(chan struct{})}
+	clusters.Store(primary, c)
+	if len(replicas) > 0 {
+		go c.Check(interval)
+	}
+	return c
+}
+
+// OpenCluster opens the primary and the replicas of c, and checks the health of the replicas until Close.
+// A replica which cannot be opened is skipped, so the reads go to the primary if there is no replica.
+// Close closes the primary and the replicas.
+func OpenCluster(ctx context.Context, c ClusterConfig) (*Cluster, error) {
+	primary, err := OpenContext(ctx, c.Primary)
+	if err != nil {
+		return nil, err
+	}
+	replicas := make([]*sql.DB, 0, len(c.Replicas))
+	for _, rc := range c.Replicas {
+		replica, er1 := OpenContext(ctx, rc)
+		if er1 != nil {
+			log.Printf("Cannot connect to replica %s: %s.", rc.Host, er1.Error())
+			continue
+		}
+		if replica == nil {
+			continue
+		}
+		replicas = append(replicas, replica)
+	}
+	balancer := RoundRobin
+	if len(c.Balancer) > 0 {
+		balancer = c.Balancer
+	}
+	interval := DefaultHealthCheck
+	if c.HealthCheck > 0 {
+		interval = time.Duration(c.HealthCheck) * time.Millisecond
+	}
+	cluster := newCluster(primary, replicas, balancer, interval)
+	cluster.owned = true
+	return cluster, nil
+}
+
+var clusters sync.Map
+
+func GetCluster(db *sql.DB) *Cluster {
+	if c, ok := clusters.Load(db); ok {
+		return c.(*Cluster)
+	}
+	return nil
+}
+
+type primaryKey struct{}
+
+// WithPrimary returns a copy of ctx, of which the reads go to the primary, to read the writes before they are replicated.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// UsePrimary returns true if the reads of ctx go to the primary.
+func UsePrimary(ctx context.Context) bool {
+	v, ok := ctx.Value(primaryKey{}).(bool)
+	return ok && v
+}
+
+// Check pings the replicas at once and then every interval, to take a replica out of the balancing when it is down, and back when it is up again, until Close.
+// It returns at once if the replicas are already checked, such as by NewCluster.
+func (c *Cluster) Check(interval time.Duration) {
+	if !atomic.CompareAndSwapInt32(&c.checking, 0, 1) {
+		return
+	}
+	c.ping(interval)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-t.C:
+			c.ping(interval)
+		}
+	}
+}
+func (c *Cluster) ping(timeout time.Duration) {
+	for i, replica := range c.Replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		if err := replica.PingContext(ctx); err != nil {
+			atomic.StoreInt32(&c.down[i], 1)
+		} else {
+			atomic.StoreInt32(&c.down[i], 0)
+		}
+		cancel()
+	}
+}
+
+// Replica returns a healthy replica by the balancer, or nil if there is no healthy replica.
+func (c *Cluster) Replica() (int, *sql.DB) {
+	n := len(c.Replicas)
+	if n == 0 {
+		return -1, nil
+	}
+	if c.Balancer == LeastConnections {
+		index := -1
+		least := 0
+		for i, replica := range c.Replicas {
+			if atomic.LoadInt32(&c.down[i]) != 0 {
+				continue
+			}
+			if inUse := replica.Stats().InUse; index < 0 || inUse < least {
+				index, least = i, inUse
+			}
+		}
+		if index < 0 {
+			return -1, nil
+		}
+		return index, c.Replicas[index]
+	}
+	start := int(atomic.AddUint32(&c.next, 1) % uint32(n))
+	for j := 0; j < n; j++ {
+		i := (start + j) % n
+		if atomic.LoadInt32(&c.down[i]) == 0 {
+			return i, c.Replicas[i]
+		}
+	}
+	return -1, nil
+}
+
+// Close stops the health check, and closes the replicas.
+// It closes the primary if the cluster is opened by OpenCluster; else the primary stays open, and its reads go to the primary again.
+func (c *Cluster) Close() error {
+	c.once.Do(func() {
+		close(c.stop)
+	})
+	var err error
+	for _, replica := range c.Replicas {
+		if er1 := Close(replica); er1 != nil && err == nil {
+			err = er1
+		}
+	}
+	if !c.owned {
+		if registered, ok := clusters.Load(c.Primary); ok && registered == c {
+			clusters.Delete(c.Primary)
+		}
+		return err
+	}
+	if er2 := Close(c.Primary); er2 != nil && err == nil {
+		err = er2
+	}
+	return err
+}
+
+var (
+	// writeWords are the words of the statements which write or lock, such as select into and the data-modifying common table expressions
+	writeWords = map[string]bool{
+		"insert": true, "update": true, "delete": true, "merge": true, "upsert": true, "into": true, "lock": true,
+		"truncate": true, "drop": true, "create": true, "alter": true, "call": true, "exec": true, "execute": true,
+	}
+	// parenWords are the keywords and the type names which are followed by parentheses, but are not function calls
+	parenWords = map[string]bool{
+		"select": true, "from": true, "join": true, "where": true, "and": true, "or": true, "not": true, "in": true, "exists": true,
+		"any": true, "all": true, "some": true, "as": true, "on": true, "using": true, "over": true, "filter": true, "within": true,
+		"values": true, "when": true, "then": true, "else": true, "case": true, "by": true, "with": true, "lateral": true, "top": true,
+		"between": true, "like": true, "ilike": true, "is": true, "distinct": true, "union": true, "intersect": true, "except": true,
+		"minus": true, "having": true, "limit": true, "offset": true, "fetch": true, "rows": true, "range": true, "against": true,
+		"numeric": true, "decimal": true, "number": true, "varchar": true, "varchar2": true, "nvarchar": true, "char": true, "nchar": true,
+		"float": true, "timestamp": true, "time": true, "datetime2": true,
+	}
+	// readFunctions are the functions which are known to be pure reads, in addition to the aggregates
+	readFunctions = map[string]bool{
+		"coalesce": true, "nullif": true, "ifnull": true, "isnull": true, "nvl": true, "iif": true, "if": true, "greatest": true, "least": true,
+		"cast": true, "convert": true, "extract": true, "date_trunc": true, "date_part": true, "to_char": true, "to_date": true, "to_timestamp": true, "to_number": true,
+		"now": true, "current_date": true, "current_timestamp": true, "getdate": true, "sysdate": true,
+		"lower": true, "upper": true, "trim": true, "ltrim": true, "rtrim": true, "length": true, "char_length": true, "len": true,
+		"substring": true, "substr": true, "concat": true, "concat_ws": true, "replace": true, "position": true, "strpos": true, "instr": true,
+		"left": true, "right": true, "lpad": true, "rpad": true, "abs": true, "round": true, "floor": true, "ceil": true, "ceiling": true, "mod": true,
+		"row_number": true, "rank": true, "dense_rank": true, "ntile": true, "lag": true, "lead": true, "first_value": true, "last_value": true,
+		"to_tsvector": true, "plainto_tsquery": true, "to_tsquery": true, "ts_rank": true, "match": true, "contains": true,
+		"json_extract": true, "json_value": true, "jsonb_extract_path": true, "unnest": true, "generate_series": true,
+	}
+)
+
+// isRead returns true for a select statement, which does not lock the rows, and calls only the functions which are known to be pure reads.
+// A statement which cannot be tokenized is not a read.
+func isRead(query string) bool {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return false
+	}
+	i := 0
+	for i < len(tokens) && tokens[i].text == "(" {
+		i++
+	}
+	if i >= len(tokens) || tokens[i].kind != tokenWord || (tokens[i].text != "select" && tokens[i].text != "with") {
+		return false
+	}
+	for j, t := range tokens {
+		call := j+1 < len(tokens) && tokens[j+1].text == "("
+		switch t.kind {
+		case tokenQuoted:
+			if call {
+				return false
+			}
+		case tokenWord:
+			if writeWords[t.text] {
+				return false
+			}
+			if t.text == "for" && j+1 < len(tokens) && (tokens[j+1].text == "share" || tokens[j+1].text == "no" || tokens[j+1].text == "key") {
+				return false
+			}
+			if call && !parenWords[t.text] && !aggregates[t.text] && !readFunctions[t.text] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// clusterExecutor runs the reads on a replica, and falls back to the primary if the replica is down.
+type clusterExecutor struct {
+	cluster *Cluster
+	primary Executor
+}
+
+func (e *clusterExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return e.primary.ExecContext(ctx, query, args...)
+}
+func (e *clusterExecutor) isRead(ctx context.Context, query string) bool {
+	if UsePrimary(ctx) {
+		return false
+	}
+	if e.cluster.IsRead != nil {
+		return e.cluster.IsRead(query)
+	}
+	return isRead(query)
+}
+func (e *clusterExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if e.isRead(ctx, query) {
+		if i, replica := e.cluster.Replica(); replica != nil {
+			rows, err := newExecutor(replica).QueryContext(ctx, query, args...)
+			if err == nil || !errors.Is(HandleError(replica, err), ErrConnection) {
+				return rows, err
+			}
+			atomic.StoreInt32(&e.cluster.down[i], 1)
+		}
+	}
+	return e.primary.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext falls back to the primary if the query of the row fails to connect to the replica.
+func (e *clusterExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if e.isRead(ctx, query) {
+		if i, replica := e.cluster.Replica(); replica != nil {
+			row := newExecutor(replica).QueryRowContext(ctx, query, args...)
+			if err := row.Err(); err == nil || !errors.Is(HandleError(replica, err), ErrConnection) {
+				return row
+			}
+			atomic.StoreInt32(&e.cluster.down[i], 1)
+		}
+	}
+	return e.primary.QueryRowContext(ctx, query, args...)
+}
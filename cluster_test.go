@@ -0,0 +1,104 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIsRead(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"select id, name from users where id = $1", true},
+		{"SELECT count(*) FROM users", true},
+		{"(select id from a) union (select id from b)", true},
+		{"with t as (select id from users) select * from t", true},
+		{"select coalesce(max(id), 0), lower(name) from users group by name", true},
+		{"select * from users where id in (select user_id from roles)", true},
+		{"select ts_rank(to_tsvector(name), plainto_tsquery($1)) as rank from users", true},
+		{"select cast(age as numeric(10, 2)) from users", true},
+		{"select 'nextval(x)' from users", true},
+		{"select * from users for update", false},
+		{"select * from users for share", false},
+		{"select * from users for no key update", false},
+		{"select nextval('users_seq')", false},
+		{"select setval('users_seq', 1)", false},
+		{"select my_function($1)", false},
+		{`select "audit"($1)`, false},
+		{"select * into backup from users", false},
+		{"with d as (delete from users returning id) select * from d", false},
+		{"insert into users(id) values ($1)", false},
+		{"update users set name = $1", false},
+		{"call refresh()", false},
+		{"select 'unterminated from users", false},
+	}
+	for _, tt := range tests {
+		if got := isRead(tt.query); got != tt.want {
+			t.Errorf("isRead(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+var errPrimary = errors.New("primary")
+
+type primaryConnector struct{}
+
+func (c primaryConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return nil, errPrimary
+}
+func (c primaryConnector) Driver() driver.Driver {
+	return nil
+}
+
+func TestClusterFallsBackToPrimary(t *testing.T) {
+	primary := sql.OpenDB(primaryConnector{})
+	SetDialect(primary, PostgresDialect{})
+	replica := openDialect(PostgresDialect{})
+	c := NewCluster(primary, []*sql.DB{replica})
+	defer c.Close()
+	e := GetExecutor(context.Background(), primary)
+	if err := e.QueryRowContext(context.Background(), "select id from users").Scan(new(string)); !errors.Is(err, errPrimary) {
+		t.Errorf("QueryRowContext() = %v, want the error of the primary", err)
+	}
+	if _, r := c.Replica(); r != nil {
+		t.Error("Replica() returns the replica which is down")
+	}
+	if _, err := e.QueryContext(context.Background(), "select id from users"); !errors.Is(err, errPrimary) {
+		t.Errorf("QueryContext() = %v, want the error of the primary", err)
+	}
+}
+
+func TestNewClusterChecksReplicas(t *testing.T) {
+	c := NewCluster(sql.OpenDB(primaryConnector{}), []*sql.DB{sql.OpenDB(routeErrorConnector{})})
+	defer c.Close()
+	for i := 0; i < 100 && atomic.LoadInt32(&c.down[0]) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if atomic.LoadInt32(&c.down[0]) == 0 {
+		t.Error("NewCluster() does not check the health of the replicas before the first interval")
+	}
+}
+
+func TestClusterCloseKeepsPrimary(t *testing.T) {
+	primary := sql.OpenDB(primaryConnector{})
+	replica := sql.OpenDB(routeErrorConnector{})
+	c := NewCluster(primary, []*sql.DB{replica})
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := primary.Ping(); !errors.Is(err, errPrimary) {
+		t.Errorf("Ping() of the primary after Close() = %v, want the error of the primary", err)
+	}
+	if err := replica.Ping(); err == nil || err.Error() != "sql: database is closed" {
+		t.Errorf("Ping() of the replica after Close() = %v, want the database is closed", err)
+	}
+	if GetCluster(primary) != nil {
+		t.Error("GetCluster() returns the cluster after Close()")
+	}
+}
@@ -1,28 +1,33 @@
 package sql
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"reflect"
 	"strconv"
 	"time"
 )
 
 type Config struct {
-	MultiStatements bool        `mapstructure:"multi_statements" json:"multiStatements,omitempty" gorm:"column:multistatements" bson:"multiStatements,omitempty" dynamodbav:"multiStatements,omitempty" firestore:"multiStatements,omitempty"`
-	DataSourceName  string      `mapstructure:"data_source_name" json:"dataSourceName,omitempty" gorm:"column:datasourcename" bson:"dataSourceName,omitempty" dynamodbav:"dataSourceName,omitempty" firestore:"dataSourceName,omitempty"`
-	Driver          string      `mapstructure:"driver" json:"driver,omitempty" gorm:"column:driver" bson:"driver,omitempty" dynamodbav:"driver,omitempty" firestore:"driver,omitempty"`
-	Host            string      `mapstructure:"host" json:"host,omitempty" gorm:"column:host" bson:"host,omitempty" dynamodbav:"host,omitempty" firestore:"host,omitempty"`
-	Port            int         `mapstructure:"port" json:"port,omitempty" gorm:"column:port" bson:"port,omitempty" dynamodbav:"port,omitempty" firestore:"port,omitempty"`
-	Database        string      `mapstructure:"database" json:"database,omitempty" gorm:"column:database" bson:"database,omitempty" dynamodbav:"database,omitempty" firestore:"database,omitempty"`
-	User            string      `mapstructure:"user" json:"user,omitempty" gorm:"column:user" bson:"user,omitempty" dynamodbav:"user,omitempty" firestore:"user,omitempty"`
-	Password        string      `mapstructure:"password" json:"password,omitempty" gorm:"column:password" bson:"password,omitempty" dynamodbav:"password,omitempty" firestore:"password,omitempty"`
-	ConnMaxLifetime int64       `mapstructure:"conn_max_lifetime" json:"connMaxLifetime,omitempty" gorm:"column:connmaxlifetime" bson:"connMaxLifetime,omitempty" dynamodbav:"connMaxLifetime,omitempty" firestore:"connMaxLifetime,omitempty"`
-	MaxIdleConns    int         `mapstructure:"max_idle_conns" json:"maxIdleConns,omitempty" gorm:"column:maxidleconns" bson:"maxIdleConns,omitempty" dynamodbav:"maxIdleConns,omitempty" firestore:"maxIdleConns,omitempty"`
-	MaxOpenConns    int         `mapstructure:"max_open_conns" json:"maxOpenConns,omitempty" gorm:"column:maxopenconns" bson:"maxOpenConns,omitempty" dynamodbav:"maxOpenConns,omitempty" firestore:"maxOpenConns,omitempty"`
-	Retry           RetryConfig `mapstructure:"retry" json:"retry,omitempty" gorm:"column:retry" bson:"retry,omitempty" dynamodbav:"retry,omitempty" firestore:"retry,omitempty"`
-	Mock            bool        `mapstructure:"mock" json:"mock,omitempty" gorm:"column:mock" bson:"mock,omitempty" dynamodbav:"mock,omitempty" firestore:"mock,omitempty"`
-	Log             bool        `mapstructure:"log" json:"log,omitempty" gorm:"column:log" bson:"log,omitempty" dynamodbav:"log,omitempty" firestore:"log,omitempty"`
+	MultiStatements bool          `mapstructure:"multi_statements" json:"multiStatements,omitempty" gorm:"column:multistatements" bson:"multiStatements,omitempty" dynamodbav:"multiStatements,omitempty" firestore:"multiStatements,omitempty"`
+	DataSourceName  string        `mapstructure:"data_source_name" json:"dataSourceName,omitempty" gorm:"column:datasourcename" bson:"dataSourceName,omitempty" dynamodbav:"dataSourceName,omitempty" firestore:"dataSourceName,omitempty"`
+	Driver          string        `mapstructure:"driver" json:"driver,omitempty" gorm:"column:driver" bson:"driver,omitempty" dynamodbav:"driver,omitempty" firestore:"driver,omitempty"`
+	Dialect         string        `mapstructure:"dialect" json:"dialect,omitempty" gorm:"column:dialect" bson:"dialect,omitempty" dynamodbav:"dialect,omitempty" firestore:"dialect,omitempty"`
+	Host            string        `mapstructure:"host" json:"host,omitempty" gorm:"column:host" bson:"host,omitempty" dynamodbav:"host,omitempty" firestore:"host,omitempty"`
+	Port            int           `mapstructure:"port" json:"port,omitempty" gorm:"column:port" bson:"port,omitempty" dynamodbav:"port,omitempty" firestore:"port,omitempty"`
+	Database        string        `mapstructure:"database" json:"database,omitempty" gorm:"column:database" bson:"database,omitempty" dynamodbav:"database,omitempty" firestore:"database,omitempty"`
+	Schema          string        `mapstructure:"schema" json:"schema,omitempty" gorm:"column:schema" bson:"schema,omitempty" dynamodbav:"schema,omitempty" firestore:"schema,omitempty"`
+	User            string        `mapstructure:"user" json:"user,omitempty" gorm:"column:user" bson:"user,omitempty" dynamodbav:"user,omitempty" firestore:"user,omitempty"`
+	Password        string        `mapstructure:"password" json:"password,omitempty" gorm:"column:password" bson:"password,omitempty" dynamodbav:"password,omitempty" firestore:"password,omitempty"`
+	ConnMaxLifetime int64         `mapstructure:"conn_max_lifetime" json:"connMaxLifetime,omitempty" gorm:"column:connmaxlifetime" bson:"connMaxLifetime,omitempty" dynamodbav:"connMaxLifetime,omitempty" firestore:"connMaxLifetime,omitempty"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns" json:"maxIdleConns,omitempty" gorm:"column:maxidleconns" bson:"maxIdleConns,omitempty" dynamodbav:"maxIdleConns,omitempty" firestore:"maxIdleConns,omitempty"`
+	MaxOpenConns    int           `mapstructure:"max_open_conns" json:"maxOpenConns,omitempty" gorm:"column:maxopenconns" bson:"maxOpenConns,omitempty" dynamodbav:"maxOpenConns,omitempty" firestore:"maxOpenConns,omitempty"`
+	Retry           RetryConfig   `mapstructure:"retry" json:"retry,omitempty" gorm:"column:retry" bson:"retry,omitempty" dynamodbav:"retry,omitempty" firestore:"retry,omitempty"`
+	Backoff         BackoffConfig `mapstructure:"backoff" json:"backoff,omitempty" gorm:"column:backoff" bson:"backoff,omitempty" dynamodbav:"backoff,omitempty" firestore:"backoff,omitempty"`
+	TxRetry         BackoffConfig `mapstructure:"tx_retry" json:"txRetry,omitempty" gorm:"column:txretry" bson:"txRetry,omitempty" dynamodbav:"txRetry,omitempty" firestore:"txRetry,omitempty"`
+	AuditUser       string        `mapstructure:"audit_user" json:"auditUser,omitempty" gorm:"column:audituser" bson:"auditUser,omitempty" dynamodbav:"auditUser,omitempty" firestore:"auditUser,omitempty"`
+	Mock            bool          `mapstructure:"mock" json:"mock,omitempty" gorm:"column:mock" bson:"mock,omitempty" dynamodbav:"mock,omitempty" firestore:"mock,omitempty"`
+	Log             bool          `mapstructure:"log" json:"log,omitempty" gorm:"column:log" bson:"log,omitempty" dynamodbav:"log,omitempty" firestore:"log,omitempty"`
 }
 type RetryConfig struct {
 	Retry1 int64 `mapstructure:"1" json:"retry1,omitempty" gorm:"column:retry1" bson:"retry1,omitempty" dynamodbav:"retry1,omitempty" firestore:"retry1,omitempty"`
@@ -36,6 +41,17 @@ type RetryConfig struct {
 	Retry9 int64 `mapstructure:"9" json:"retry9,omitempty" gorm:"column:retry9" bson:"retry9,omitempty" dynamodbav:"retry9,omitempty" firestore:"retry9,omitempty"`
 }
 
+// BackoffConfig configures an exponential backoff, with intervals and elapsed time in milliseconds.
+// It is used by OpenByConfig to connect, and by TxRetry to retry statements and transactions on deadlock and serialization failure.
+type BackoffConfig struct {
+	MaxAttempts     int     `mapstructure:"max_attempts" json:"maxAttempts,omitempty" gorm:"column:maxattempts" bson:"maxAttempts,omitempty" dynamodbav:"maxAttempts,omitempty" firestore:"maxAttempts,omitempty"`
+	InitialInterval int64   `mapstructure:"initial_interval" json:"initialInterval,omitempty" gorm:"column:initialinterval" bson:"initialInterval,omitempty" dynamodbav:"initialInterval,omitempty" firestore:"initialInterval,omitempty"`
+	MaxInterval     int64   `mapstructure:"max_interval" json:"maxInterval,omitempty" gorm:"column:maxinterval" bson:"maxInterval,omitempty" dynamodbav:"maxInterval,omitempty" firestore:"maxInterval,omitempty"`
+	Multiplier      float64 `mapstructure:"multiplier" json:"multiplier,omitempty" gorm:"column:multiplier" bson:"multiplier,omitempty" dynamodbav:"multiplier,omitempty" firestore:"multiplier,omitempty"`
+	Jitter          float64 `mapstructure:"jitter" json:"jitter,omitempty" gorm:"column:jitter" bson:"jitter,omitempty" dynamodbav:"jitter,omitempty" firestore:"jitter,omitempty"`
+	MaxElapsedTime  int64   `mapstructure:"max_elapsed_time" json:"maxElapsedTime,omitempty" gorm:"column:maxelapsedtime" bson:"maxElapsedTime,omitempty" dynamodbav:"maxElapsedTime,omitempty" firestore:"maxElapsedTime,omitempty"`
+}
+
 func MakeDurations(vs []int64) []time.Duration {
 	durations := make([]time.Duration, 0)
 	for _, v := range vs {
@@ -62,19 +78,39 @@ func DurationsFromValue(v interface{}, prefix string, max int) []time.Duration {
 	arr := MakeArray(v, prefix, max)
 	return MakeDurations(arr)
 }
-func Retry(sleeps []time.Duration, f func() error) (err error) {
-	attempts := len(sleeps)
-	for i := 0; ; i++ {
-		log.Printf("Retrying %d of %d ", i+1, attempts)
-		err = f()
+
+// Retry calls f until it succeeds, at most len(sleeps) times, waiting sleeps[i] after the failure of attempt i+1; the last sleep is not used.
+// The optional logger is called after each failure, as by RetryContext.
+func Retry(sleeps []time.Duration, f func() error, options ...func(format string, args ...interface{})) error {
+	delays := sleeps
+	if len(delays) > 0 {
+		delays = delays[:len(delays)-1]
+	}
+	return RetryContext(context.Background(), NewFixedBackoff(delays...), f, options...)
+}
+
+// RetryContext calls f until it succeeds, the backoff stops, or ctx is done.
+// The optional logger is called after each failure, before waiting for the next attempt.
+func RetryContext(ctx context.Context, b Backoff, f func() error, options ...func(format string, args ...interface{})) error {
+	var logf func(format string, args ...interface{})
+	if len(options) > 0 {
+		logf = options[0]
+	}
+	start := time.Now()
+	for i := 1; ; i++ {
+		err := f()
 		if err == nil {
-			return
+			return nil
+		}
+		d, ok := b.NextDelay(i, time.Since(start))
+		if !ok {
+			return fmt.Errorf("after %d attempts, last error: %w", i, err)
+		}
+		if logf != nil {
+			logf("Attempt %d failed, retrying after %s: %s", i, d, err.Error())
 		}
-		if i >= (attempts - 1) {
-			break
+		if er2 := Sleep(ctx, d); er2 != nil {
+			return fmt.Errorf("%w, last error: %v", er2, err)
 		}
-		time.Sleep(sleeps[i])
-		log.Printf("Retrying %d of %d after error: %s", i+1, attempts, err.Error())
 	}
-	return fmt.Errorf("after %d attempts, last error: %s", attempts, err)
 }
@@ -0,0 +1,49 @@
+package sql
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRetryAttempts(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	tests := []struct {
+		sleeps   []time.Duration
+		attempts int
+	}{
+		{nil, 1},
+		{[]time.Duration{time.Millisecond}, 1},
+		{[]time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}, 3},
+	}
+	for _, tt := range tests {
+		calls, logs := 0, 0
+		err := Retry(tt.sleeps, func() error {
+			calls++
+			return errors.New("failed")
+		}, func(format string, args ...interface{}) {
+			logs++
+		})
+		if err == nil || calls != tt.attempts || logs != tt.attempts-1 {
+			t.Errorf("Retry(%v) = %v after %d attempts and %d logs, want %d attempts", tt.sleeps, err, calls, logs, tt.attempts)
+		}
+	}
+	calls := 0
+	if err := Retry([]time.Duration{time.Millisecond, time.Millisecond}, func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("failed")
+		}
+		return nil
+	}); err != nil || calls != 2 {
+		t.Errorf("Retry() = %v after %d attempts, want success after 2 attempts", err, calls)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("Retry() logs to the standard logger: %q", buf.String())
+	}
+}
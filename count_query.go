@@ -0,0 +1,269 @@
+package sql
+
+import (
+	"errors"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenQuoted
+	tokenParam
+	tokenSymbol
+)
+
+// token is a token of a sql statement. The text of a word is in lower case, and depth is the number of the parentheses around the token.
+type token struct {
+	kind  tokenKind
+	text  string
+	start int
+	end   int
+	depth int
+}
+
+var errSyntax = errors.New("cannot tokenize sql")
+
+// tokenize splits sql into tokens, without the spaces and the comments.
+// It returns an error for an unterminated string, quoted identifier or comment, and for unbalanced parentheses.
+func tokenize(sql string) ([]token, error) {
+	tokens := make([]token, 0)
+	depth := 0
+	n := len(sql)
+	for i := 0; i < n; {
+		c := sql[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+			continue
+		case c == '-' && i+1 < n && sql[i+1] == '-':
+			for i < n && sql[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '/' && i+1 < n && sql[i+1] == '*':
+			k := strings.Index(sql[i+2:], "*/")
+			if k < 0 {
+				return nil, errSyntax
+			}
+			i += k + 4
+			continue
+		case c == '\'':
+			escape := start > 0 && (sql[start-1] == 'E' || sql[start-1] == 'e') && (start == 1 || !isWordChar(sql[start-2]))
+			end, ok := closeQuote(sql, i, '\'', escape)
+			if !ok {
+				return nil, errSyntax
+			}
+			i = end
+			tokens = append(tokens, token{kind: tokenString, text: sql[start:i], start: start, end: i, depth: depth})
+			continue
+		case c == '"' || c == '`' || c == '[':
+			q := c
+			if c == '[' {
+				q = ']'
+			}
+			end, ok := closeQuote(sql, i, q, false)
+			if !ok {
+				return nil, errSyntax
+			}
+			i = end
+			tokens = append(tokens, token{kind: tokenQuoted, text: sql[start:i], start: start, end: i, depth: depth})
+			continue
+		case c == '(':
+			i++
+			tokens = append(tokens, token{kind: tokenSymbol, text: "(", start: start, end: i, depth: depth})
+			depth++
+			continue
+		case c == ')':
+			if depth == 0 {
+				return nil, errSyntax
+			}
+			depth--
+			i++
+			tokens = append(tokens, token{kind: tokenSymbol, text: ")", start: start, end: i, depth: depth})
+			continue
+		case c == '?':
+			i++
+			tokens = append(tokens, token{kind: tokenParam, text: "?", start: start, end: i, depth: depth})
+			continue
+		case (c == '$' || c == ':' || c == '@') && i+1 < n && isWordChar(sql[i+1]) && !(c == ':' && start > 0 && sql[start-1] == ':'):
+			i++
+			for i < n && isWordChar(sql[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenParam, text: sql[start:i], start: start, end: i, depth: depth})
+			continue
+		case isWordChar(c):
+			for i < n && (isWordChar(sql[i]) || sql[i] == '$') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: strings.ToLower(sql[start:i]), start: start, end: i, depth: depth})
+			continue
+		}
+		i++
+		tokens = append(tokens, token{kind: tokenSymbol, text: sql[start:i], start: start, end: i, depth: depth})
+	}
+	if depth != 0 {
+		return nil, errSyntax
+	}
+	return tokens, nil
+}
+func isWordChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// closeQuote returns the end of the quoted text, which starts at i. A doubled quote q is a quote in the text, and so is \q if escape is true.
+func closeQuote(sql string, i int, q byte, escape bool) (int, bool) {
+	n := len(sql)
+	for j := i + 1; j < n; j++ {
+		switch {
+		case escape && sql[j] == '\\':
+			j++
+		case sql[j] == q:
+			if j+1 < n && sql[j+1] == q {
+				j++
+				continue
+			}
+			return j + 1, true
+		}
+	}
+	return n, false
+}
+
+var aggregates = map[string]bool{
+	"count": true, "sum": true, "avg": true, "min": true, "max": true,
+	"array_agg": true, "string_agg": true, "json_agg": true, "jsonb_agg": true, "json_object_agg": true, "group_concat": true, "listagg": true, "xmlagg": true,
+	"bool_and": true, "bool_or": true, "every": true, "stddev": true, "variance": true,
+}
+
+// buildCount returns the count query of sql, which is a select statement, optionally with common table expressions.
+// The select list is replaced by count(*) if the statement returns a row per row of its from clause;
+// otherwise, for distinct, group by, having, union, aggregates and limit, the statement is counted in a subquery.
+// The order by clause is removed, unless the statement is limited or the clause has a parameter, and so is the locking clause.
+func buildCount(sql string) (string, bool) {
+	tokens, err := tokenize(sql)
+	if err != nil {
+		return "", false
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1].text == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 {
+		return "", false
+	}
+	main := 0
+	switch tokens[0].text {
+	case "select":
+	case "with":
+		main = -1
+		for i := 1; i < len(tokens); i++ {
+			if tokens[i].depth == 0 && tokens[i].kind == tokenWord && tokens[i].text == "select" {
+				main = i
+				break
+			}
+		}
+		if main < 0 {
+			return "", false
+		}
+	case "(":
+		main = 0
+	default:
+		return "", false
+	}
+	wrap := tokens[main].text != "select"
+	limited := false
+	from, order, end := -1, -1, len(tokens)
+	for i := main + 1; i < end; i++ {
+		t := tokens[i]
+		if t.depth != 0 {
+			continue
+		}
+		if t.kind == tokenSymbol && t.text == "(" && i > main+1 && from < 0 && tokens[i-1].kind == tokenWord && aggregates[tokens[i-1].text] {
+			wrap = true
+		}
+		if t.kind != tokenWord {
+			continue
+		}
+		switch t.text {
+		case "distinct", "top":
+			if i == main+1 {
+				wrap = true
+				limited = limited || t.text == "top"
+			}
+		case "from":
+			if from < 0 {
+				from = i
+			}
+		case "group", "having", "window", "union", "intersect", "except", "minus":
+			wrap = true
+		case "order":
+			if i+1 < end && tokens[i+1].text == "by" && order < 0 {
+				order = i
+			}
+		case "limit", "offset", "fetch":
+			wrap = true
+			limited = true
+		case "for":
+			if i+1 < end && tokens[i+1].kind == tokenWord && (tokens[i+1].text == "update" || tokens[i+1].text == "share" || tokens[i+1].text == "no" || tokens[i+1].text == "key") {
+				end = i
+			}
+		}
+	}
+	if from < 0 {
+		wrap = true
+	}
+	cut := end
+	if order >= 0 && !limited {
+		cut = order
+		for i := order; i < end; i++ {
+			if tokens[i].kind == tokenParam {
+				// the parameters must be kept in their positions
+				cut = end
+				wrap = true
+				break
+			}
+		}
+	}
+	prefix := sql[:tokens[main].start]
+	body := sql[tokens[main].start:tokens[cut-1].end]
+	if !wrap {
+		return prefix + "select count(*) as total " + sql[tokens[from].start:tokens[cut-1].end], true
+	}
+	return prefix + "select count(*) as total from (" + body + ") main", true
+}
+
+// buildWindowCount adds count(*) over() as the first column of sql, which is a select statement, so that each row has the number of the rows of the statement.
+// It returns false for the statements of which the rows are not the rows counted by the window, such as distinct, union and limit.
+func buildWindowCount(sql string) (string, bool) {
+	tokens, err := tokenize(sql)
+	if err != nil || len(tokens) < 2 {
+		return "", false
+	}
+	main := -1
+	for i, t := range tokens {
+		if t.depth == 0 && t.kind == tokenWord && t.text == "select" {
+			main = i
+			break
+		}
+	}
+	if main < 0 || (main > 0 && tokens[0].text != "with") {
+		return "", false
+	}
+	if next := tokens[main+1]; next.kind == tokenWord && (next.text == "distinct" || next.text == "top") {
+		return "", false
+	}
+	for _, t := range tokens[main+1:] {
+		if t.depth != 0 || t.kind != tokenWord {
+			continue
+		}
+		switch t.text {
+		case "union", "intersect", "except", "minus", "limit", "offset", "fetch":
+			return "", false
+		}
+	}
+	i := tokens[main].end
+	return sql[:i] + " count(*) over() as total," + sql[i:], true
+}
@@ -0,0 +1,85 @@
+package sql
+
+import "testing"
+
+func TestBuildCount(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"select", "select id, name from users where status = $1",
+			"select count(*) as total from users where status = $1"},
+		{"upper case", "SELECT id, name FROM users WHERE status = ?",
+			"select count(*) as total FROM users WHERE status = ?"},
+		{"subquery in the select list", "select id, (select count(*) from roles r where r.user_id = u.id) as roles from users u",
+			"select count(*) as total from users u"},
+		{"from in a string", "select 'from' as f, id from users",
+			"select count(*) as total from users"},
+		{"order by", "select id from users where status = $1 order by name desc",
+			"select count(*) as total from users where status = $1"},
+		{"order by with a parameter", "select id from users where status = $1 order by position(name in $2)",
+			"select count(*) as total from (select id from users where status = $1 order by position(name in $2)) main"},
+		{"group by", "select status, count(*) from users group by status",
+			"select count(*) as total from (select status, count(*) from users group by status) main"},
+		{"aggregate", "select max(age) from users",
+			"select count(*) as total from (select max(age) from users) main"},
+		{"distinct", "select distinct status from users order by status",
+			"select count(*) as total from (select distinct status from users) main"},
+		{"union", "select id from a union select id from b order by id",
+			"select count(*) as total from (select id from a union select id from b) main"},
+		{"cte", "with t as (select id, status from users order by id) select id from t where status = $1 order by id",
+			"with t as (select id, status from users order by id) select count(*) as total from t where status = $1"},
+		{"limit", "select id from users order by id limit 10",
+			"select count(*) as total from (select id from users order by id limit 10) main"},
+		{"top", "select top 10 id from users order by id",
+			"select count(*) as total from (select top 10 id from users order by id) main"},
+		{"for update", "select id from users where status = $1 for update",
+			"select count(*) as total from users where status = $1"},
+		{"for update after order by", "SELECT id FROM users ORDER BY id FOR UPDATE;",
+			"select count(*) as total FROM users"},
+		{"comment", "select id -- from comments\nfrom users /* order by */ order by id",
+			"select count(*) as total from users"},
+	}
+	for _, tt := range tests {
+		if got, ok := buildCount(tt.query); !ok || got != tt.want {
+			t.Errorf("%s: buildCount(%q) = %q, %v, want %q", tt.name, tt.query, got, ok, tt.want)
+		}
+	}
+	for _, query := range []string{"", "select 'unterminated from users", "select (id from users", "update users set a = 1"} {
+		if got, ok := buildCount(query); ok {
+			t.Errorf("buildCount(%q) = %q, want false", query, got)
+		}
+	}
+}
+
+func TestBuildWindowCount(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+		ok    bool
+	}{
+		{"select id from users where status = $1 order by id", "select count(*) over() as total, id from users where status = $1 order by id", true},
+		{"SELECT status, count(*) FROM users GROUP BY status", "SELECT count(*) over() as total, status, count(*) FROM users GROUP BY status", true},
+		{"with t as (select id from users limit 5) select id from t", "with t as (select id from users limit 5) select count(*) over() as total, id from t", true},
+		{"select distinct status from users", "", false},
+		{"select top 10 id from users", "", false},
+		{"select id from a union select id from b", "", false},
+		{"select id from users limit 10", "", false},
+		{"select 'x", "", false},
+		{"update users set a = 1", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := buildWindowCount(tt.query); ok != tt.ok || got != tt.want {
+			t.Errorf("buildWindowCount(%q) = %q, %v, want %q, %v", tt.query, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBuildCountQueryFallback(t *testing.T) {
+	params := []interface{}{1}
+	query, values := BuildCountQuery("select id from users where name = 'it''s' and note = 'a order by id", params)
+	if want := "select count(*) as total  from users where name = 'it''s' and note = 'a"; query != want || len(values) != 1 {
+		t.Errorf("BuildCountQuery() = %q %v, want %q", query, values, want)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"reflect"
 	"sort"
 	"strconv"
@@ -27,24 +28,50 @@ const (
 )
 
 func OpenByConfig(c Config) (*sql.DB, error) {
+	return OpenContext(context.Background(), c)
+}
+
+// OpenContext opens and pings the database, retrying with the backoff in options, or else with c.Backoff, or else with c.Retry.
+// It stops retrying when ctx is done.
+func OpenContext(ctx context.Context, c Config, options ...Backoff) (*sql.DB, error) {
 	if c.Mock {
 		return nil, nil
 	}
-	if c.Retry.Retry1 <= 0 {
-		return open(c)
+	var b Backoff
+	if len(options) > 0 && options[0] != nil {
+		b = options[0]
+	} else if c.Backoff.MaxAttempts > 1 || c.Backoff.MaxElapsedTime > 0 {
+		b = NewBackoff(c.Backoff)
+	} else if c.Retry.Retry1 > 0 {
+		b = NewFixedBackoff(DurationsFromValue(c.Retry, "Retry", 9)...)
 	} else {
-		durations := DurationsFromValue(c.Retry, "Retry", 9)
-		return Open(c, durations...)
+		return openContext(ctx, c)
 	}
+	var db *sql.DB
+	err := RetryContext(ctx, b, func() error {
+		db2, er2 := openContext(ctx, c)
+		if er2 == nil {
+			db = db2
+		}
+		return er2
+	}, log.Printf)
+	if err != nil {
+		log.Printf("Cannot connect to database: %s.", err.Error())
+	}
+	return db, err
 }
-func open(c Config) (*sql.DB, error) {
+func openContext(ctx context.Context, c Config) (*sql.DB, error) {
 	dsn := c.DataSourceName
 	if len(dsn) == 0 {
 		dsn = BuildDataSourceName(c)
 	}
 	db, err := sql.Open(c.Driver, dsn)
 	if err != nil {
-		return db, err
+		return nil, err
+	}
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
 	}
 	if c.ConnMaxLifetime > 0 {
 		db.SetConnMaxLifetime(time.Duration(c.ConnMaxLifetime) * time.Second)
@@ -55,39 +82,65 @@ func open(c Config) (*sql.DB, error) {
 	if c.MaxOpenConns > 0 {
 		db.SetMaxOpenConns(c.MaxOpenConns)
 	}
-	return db, err
+	if len(c.Dialect) > 0 {
+		d, ok := GetDialectByName(c.Dialect)
+		if !ok {
+			db.Close()
+			return nil, fmt.Errorf("dialect %s is not registered", c.Dialect)
+		}
+		SetDialect(db, d)
+	} else if d, ok := GetDialectByName(c.Driver); ok {
+		SetDialect(db, d)
+	}
+	txRetry := c.TxRetry
+	if r, ok := GetDialect(db).(TxRetrier); ok && txRetry.MaxAttempts <= 1 && txRetry.MaxElapsedTime <= 0 {
+		txRetry = r.TxRetry()
+	}
+	SetRetryPolicy(db, NewRetryPolicy(txRetry))
+	SetAuditUser(db, c.AuditUser)
+	return db, nil
 }
 func Open(c Config, retries ...time.Duration) (*sql.DB, error) {
-	if c.Mock {
-		return nil, nil
-	}
 	if len(retries) == 0 {
-		return open(c)
-	} else {
-		db, er1 := open(c)
-		if er1 == nil {
-			return db, er1
-		}
-		i := 0
-		err := Retry(retries, func() (err error) {
-			i = i + 1
-			db2, er2 := open(c)
-			if er2 == nil {
-				db = db2
-			}
-			return er2
-		})
-		if err != nil {
-			log.Printf("Cannot conect to database: %s.", err.Error())
-		}
-		return db, err
+		return OpenContext(context.Background(), c, nil)
+	}
+	return OpenContext(context.Background(), c, NewFixedBackoff(retries...))
+}
+
+// Close closes db, and removes the dialect, the retry policy, the audit user, the router and the cluster registered for db, so that they are not kept after db.
+// The databases of the tenants of its router are closed too; the replicas of its cluster are closed by Cluster.Close.
+func Close(db *sql.DB) error {
+	if r := GetRouter(db); r != nil {
+		r.Close()
 	}
+	clusters.Delete(db)
+	retryPolicies.Delete(db)
+	auditUsers.Delete(db)
+	dbDialects.Delete(db)
+	return db.Close()
 }
+// BuildDataSourceName builds the data source name of c. The schema of c is the search path of postgres and cockroach, and the database of mysql, mariadb and tidb.
 func BuildDataSourceName(c Config) string {
-	if c.Driver == "postgres" {
+	driver := c.Driver
+	if len(c.Dialect) > 0 {
+		driver = c.Dialect
+	}
+	if driver == "postgres" || driver == "pgx" {
 		uri := fmt.Sprintf("user=%s dbname=%s password=%s host=%s port=%d sslmode=disable", c.User, c.Database, c.Password, c.Host, c.Port)
+		if len(c.Schema) > 0 {
+			uri = uri + " search_path=" + c.Schema
+		}
 		return uri
-	} else if c.Driver == "mysql" {
+	} else if driver == DriverCockroach || driver == "cockroachdb" {
+		u := url.URL{Scheme: "postgresql", User: url.UserPassword(c.User, c.Password), Host: fmt.Sprintf("%s:%d", c.Host, c.Port), Path: "/" + c.Database, RawQuery: "sslmode=disable"}
+		if len(c.Schema) > 0 {
+			u.RawQuery = u.RawQuery + "&options=" + url.QueryEscape("-csearch_path="+c.Schema)
+		}
+		return u.String()
+	} else if driver == "mysql" || driver == DriverMariaDB || driver == DriverTiDB {
+		if len(c.Schema) > 0 {
+			c.Database = c.Schema
+		}
 		uri := ""
 		if c.MultiStatements {
 			uri = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8&parseTime=True&loc=Local&multiStatements=True", c.User, c.Password, c.Host, c.Port, c.Database)
@@ -95,10 +148,10 @@ func BuildDataSourceName(c Config) string {
 		}
 		uri = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8&parseTime=True&loc=Local", c.User, c.Password, c.Host, c.Port, c.Database)
 		return uri
-	} else if c.Driver == "mssql" { // mssql
+	} else if driver == "mssql" || driver == "sqlserver" { // mssql
 		uri := fmt.Sprintf("sqlserver://%s:%s@%s:%d?Database=%s", c.User, c.Password, c.Host, c.Port, c.Database)
 		return uri
-	} else if c.Driver == "godror" || c.Driver == "oracle" {
+	} else if driver == "godror" || driver == "oracle" {
 		return fmt.Sprintf("user=\"%s\" password=\"%s\" connectString=\"%s:%d/%s\"", c.User, c.Password, c.Host, c.Port, c.Database)
 	} else { //sqlite
 		return c.Host // return sql.Open("sqlite3", c.Host)
@@ -116,8 +169,9 @@ func BuildFindById(db *sql.DB, table string, id interface{}, mapJsonColumnKeys m
 	} else {
 		buildParam = GetBuild(db)
 	}
+	dialect := GetDialect(db)
 	if len(keys) == 1 {
-		where = fmt.Sprintf("where %s = %s", mapJsonColumnKeys[keys[0]], buildParam(1))
+		where = fmt.Sprintf("where %s = %s", QuoteName(dialect, mapJsonColumnKeys[keys[0]]), buildParam(1))
 		values = append(values, id)
 	} else {
 		conditions := make([]string, 0)
@@ -126,7 +180,7 @@ func BuildFindById(db *sql.DB, table string, id interface{}, mapJsonColumnKeys m
 			for _, keyJson := range keys {
 				columnName := mapJsonColumnKeys[keyJson]
 				if idk, ok1 := ids[keyJson]; ok1 {
-					conditions = append(conditions, fmt.Sprintf("%s = %s", columnName, buildParam(j)))
+					conditions = append(conditions, fmt.Sprintf("%s = %s", QuoteName(dialect, columnName), buildParam(j)))
 					values = append(values, idk)
 					j++
 				}
@@ -134,11 +188,11 @@ func BuildFindById(db *sql.DB, table string, id interface{}, mapJsonColumnKeys m
 			where = "where " + strings.Join(conditions, " and ")
 		}
 	}
-	return fmt.Sprintf("select * from %v %v", table, where), values
+	return fmt.Sprintf("select * from %v %v", QuoteName(dialect, table), where), values
 }
 
-func BuildSelectAllQuery(table string) string {
-	return fmt.Sprintf("select * from %v", table)
+func BuildSelectAllQuery(table string, options ...Dialect) string {
+	return fmt.Sprintf("select * from %v", QuoteName(getDialect(options), table))
 }
 
 func InitSingleResult(modelType reflect.Type) interface{} {
@@ -200,32 +254,87 @@ func Insert(ctx context.Context, db *sql.DB, table string, model interface{}, op
 	} else {
 		buildParam = GetBuild(db)
 	}
-	queryInsert, values := BuildInsert(table, model, 0, buildParam)
+	if err := SetTenant(ctx, model); err != nil {
+		return 0, err
+	}
+	dialect := GetDialect(db)
+	queryInsert, values := BuildInsert(table, model, 1, buildParam, dialect)
 
-	result, err := db.ExecContext(ctx, queryInsert, values...)
+	result, err := ExecInsert(ctx, GetExecutor(ctx, db), dialect, model, queryInsert, values...)
 	if err != nil {
+		return 0, HandleError(db, err)
+	}
+	return result, nil
+}
+
+// ExecInsert executes the insert statement of model. If model is a pointer to a struct with a zero auto-increment field, the generated key is set to that field.
+func ExecInsert(ctx context.Context, exec Executor, dialect Dialect, model interface{}, query string, values ...interface{}) (int64, error) {
+	mv := reflect.ValueOf(model)
+	index := -1
+	var column string
+	if mv.Kind() == reflect.Ptr && mv.Elem().Kind() == reflect.Struct {
+		index, column = FindAutoIncrement(mv.Elem().Type())
+	}
+	if index < 0 || !mv.Elem().Field(index).IsZero() {
+		result, err := exec.ExecContext(ctx, query, values...)
 		if err != nil {
-			return handleDuplicate(db, err)
+			return 0, err
+		}
+		return result.RowsAffected()
+	}
+	field := mv.Elem().Field(index)
+	column = QuoteName(dialect, column)
+	switch dialect.GeneratedKey() {
+	case KeyReturning:
+		if r, ok := dialect.(Returner); ok {
+			err := exec.QueryRowContext(ctx, query+r.Returning(column), values...).Scan(field.Addr().Interface())
+			if err != nil {
+				return 0, err
+			}
+			return 1, nil
 		}
+	case KeyOutputInserted:
+		query = strings.Replace(query, ")values(", ") output inserted."+column+" values(", 1)
+		err := exec.QueryRowContext(ctx, query, values...).Scan(field.Addr().Interface())
+		if err != nil {
+			return 0, err
+		}
+		return 1, nil
+	case KeyReturningInto:
+		query = query + " returning " + column + " into " + dialect.BuildParam(len(values)+1)
+		values = append(values, sql.Out{Dest: field.Addr().Interface()})
+	case KeyLastInsertId:
+		result, err := exec.ExecContext(ctx, query, values...)
+		if err != nil {
+			return 0, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		setGeneratedKey(field, id)
+		return result.RowsAffected()
+	}
+	result, err := exec.ExecContext(ctx, query, values...)
+	if err != nil {
+		return 0, err
 	}
 	return result.RowsAffected()
 }
-
-func handleDuplicate(db *sql.DB, err error) (int64, error) {
-	x := err.Error()
-	driver := GetDriver(db)
-	if driver == DriverPostgres && strings.Contains(x, "pq: duplicate key value violates unique constraint") {
-		return 0, nil
-	} else if driver == DriverMysql && strings.Contains(x, "Error 1062: Duplicate entry") {
-		return 0, nil //mysql Error 1062: Duplicate entry 'a-1' for key 'PRIMARY'
-	} else if driver == DriverOracle && strings.Contains(x, "ORA-00001: unique constraint") {
-		return 0, nil //mysql Error 1062: Duplicate entry 'a-1' for key 'PRIMARY'
-	} else if driver == DriverMssql && strings.Contains(x, "Violation of PRIMARY KEY constraint") {
-		return 0, nil //Violation of PRIMARY KEY constraint 'PK_aa'. Cannot insert duplicate key in object 'dbo.aa'. The duplicate key value is (b, 2).
-	} else if driver == DriverSqlite3 && strings.Contains(x, "UNIQUE constraint failed") {
-		return 0, nil
+func setGeneratedKey(field reflect.Value, id int64) {
+	if field.Kind() == reflect.Ptr {
+		v := reflect.New(field.Type().Elem())
+		field.Set(v)
+		field = v.Elem()
+	}
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		field.SetInt(id)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.SetUint(uint64(id))
+	case reflect.String:
+		field.SetString(strconv.FormatInt(id, 10))
 	}
-	return 0, err
 }
 
 func InsertTx(ctx context.Context, db *sql.DB, tx *sql.Tx, table string, model interface{}, options ...func(i int) string) (int64, error) {
@@ -235,12 +344,16 @@ func InsertTx(ctx context.Context, db *sql.DB, tx *sql.Tx, table string, model i
 	} else {
 		buildParam = GetBuild(db)
 	}
-	queryInsert, values := BuildInsert(table, model, 0, buildParam)
-	result, err := tx.ExecContext(ctx, queryInsert, values...)
+	if err := SetTenant(ctx, model); err != nil {
+		return 0, err
+	}
+	dialect := GetDialect(db)
+	queryInsert, values := BuildInsert(table, model, 1, buildParam, dialect)
+	result, err := ExecInsert(ctx, tx, dialect, model, queryInsert, values...)
 	if err != nil {
-		return handleDuplicate(db, err)
+		return 0, HandleError(db, err)
 	}
-	return result.RowsAffected()
+	return result, nil
 }
 
 func InsertWithVersion(ctx context.Context, db *sql.DB, table string, model interface{}, versionIndex int, options ...func(i int) string) (int64, error) {
@@ -253,27 +366,23 @@ func InsertWithVersion(ctx context.Context, db *sql.DB, table string, model inte
 	} else {
 		buildParam = GetBuild(db)
 	}
-	queryInsert, values := BuildInsertWithVersion(table, model, 0, versionIndex, buildParam)
+	return insertWithVersion(ctx, db, table, model, versionIndex, getVersionStrategy(model, versionIndex), buildParam)
+}
+func insertWithVersion(ctx context.Context, db *sql.DB, table string, model interface{}, versionIndex int, strategy VersionStrategy, buildParam func(i int) string) (int64, error) {
+	if err := SetTenant(ctx, model); err != nil {
+		return 0, err
+	}
+	dialect := GetDialect(db)
+	queryInsert, values, err := buildInsertWithVersion(table, model, 1, versionIndex, strategy, buildParam, dialect)
+	if err != nil {
+		return 0, err
+	}
 
-	result, err := db.ExecContext(ctx, queryInsert, values...)
+	result, err := ExecInsert(ctx, GetExecutor(ctx, db), dialect, model, queryInsert, values...)
 	if err != nil {
-		errstr := err.Error()
-		driver := GetDriver(db)
-		if driver == DriverPostgres && strings.Contains(errstr, "pq: duplicate key value violates unique constraint") {
-			return 0, nil
-		} else if driver == DriverMysql && strings.Contains(errstr, "Error 1062: Duplicate entry") {
-			return 0, nil //mysql Error 1062: Duplicate entry 'a-1' for key 'PRIMARY'
-		} else if driver == DriverOracle && strings.Contains(errstr, "ORA-00001: unique constraint") {
-			return 0, nil //mysql Error 1062: Duplicate entry 'a-1' for key 'PRIMARY'
-		} else if driver == DriverMssql && strings.Contains(errstr, "Violation of PRIMARY KEY constraint") {
-			return 0, nil //Violation of PRIMARY KEY constraint 'PK_aa'. Cannot insert duplicate key in object 'dbo.aa'. The duplicate key value is (b, 2).
-		} else if driver == DriverSqlite3 && strings.Contains(errstr, "UNIQUE constraint failed") {
-			return 0, nil
-		} else {
-			return 0, err
-		}
+		return 0, HandleError(db, err)
 	}
-	return result.RowsAffected()
+	return result, nil
 }
 
 func Exec(ctx context.Context, stmt *sql.Stmt, values ...interface{}) (int64, error) {
@@ -291,10 +400,16 @@ func Update(ctx context.Context, db *sql.DB, table string, model interface{}, op
 	} else {
 		buildParam = GetBuild(db)
 	}
-	query, values := BuildUpdate(table, model, 0, buildParam)
-	r, err0 := db.ExecContext(ctx, query, values...)
+	t, tenant, err := tenantOf(ctx, model)
+	if err != nil {
+		return -1, err
+	}
+	dialect := GetDialect(db)
+	query, values := BuildUpdate(table, model, 0, buildParam, dialect)
+	query, values = t.Where(dialect, query, values, tenant, buildParam)
+	r, err0 := GetExecutor(ctx, db).ExecContext(ctx, query, values...)
 	if err0 != nil {
-		return -1, err0
+		return -1, HandleError(db, err0)
 	}
 	return r.RowsAffected()
 }
@@ -306,14 +421,21 @@ func UpdateTx(ctx context.Context, db *sql.DB, tx *sql.Tx, table string, model i
 	} else {
 		buildParam = GetBuild(db)
 	}
-	query, values := BuildUpdate(table, model, 0, buildParam)
+	t, tenant, err := tenantOf(ctx, model)
+	if err != nil {
+		return -1, err
+	}
+	dialect := GetDialect(db)
+	query, values := BuildUpdate(table, model, 0, buildParam, dialect)
+	query, values = t.Where(dialect, query, values, tenant, buildParam)
 	r, err0 := tx.ExecContext(ctx, query, values...)
 	if err0 != nil {
-		return -1, err0
+		return -1, HandleError(db, err0)
 	}
 	return r.RowsAffected()
 }
 
+// UpdateWithVersion returns a *VersionError of ErrNotFound or ErrVersionConflict if no row is updated, and then the version of model is not changed.
 func UpdateWithVersion(ctx context.Context, db *sql.DB, table string, model interface{}, versionIndex int, options ...func(i int) string) (int64, error) {
 	if versionIndex < 0 {
 		return 0, errors.New("version's index not found")
@@ -325,14 +447,49 @@ func UpdateWithVersion(ctx context.Context, db *sql.DB, table string, model inte
 	} else {
 		buildParam = GetBuild(db)
 	}
-	query, values := BuildUpdateWithVersion(table, model, 0, versionIndex, buildParam)
-
-	result, err := db.ExecContext(ctx, query, values...)
+	return updateWithVersion(ctx, db, table, model, versionIndex, getVersionStrategy(model, versionIndex), buildParam, false)
+}
 
+// updateWithVersion fills model with the stored row if returning is true.
+func updateWithVersion(ctx context.Context, db *sql.DB, table string, model interface{}, versionIndex int, strategy VersionStrategy, buildParam func(i int) string, returning bool) (int64, error) {
+	versionColName, ok := GetColumnNameByIndex(reflect.Indirect(reflect.ValueOf(model)).Type(), versionIndex)
+	if !ok {
+		return 0, errors.New("version's column not found")
+	}
+	t, tenant, err := tenantOf(ctx, model)
 	if err != nil {
 		return -1, err
 	}
-	return result.RowsAffected()
+	currentVersion := getVersion(model, versionIndex)
+	dialect := GetDialect(db)
+	query, values, err := buildUpdateWithVersion(table, model, 0, versionIndex, versionColName, strategy, buildParam, dialect)
+	if err != nil {
+		return -1, err
+	}
+	query, values = t.Where(dialect, query, values, tenant, buildParam)
+	_, keys, _, _ := BuildMapDataAndKeys(model, true)
+	if t != nil {
+		keys[t.Column] = tenant
+	}
+	var count int64
+	if returning {
+		count, err = ExecReturning(ctx, db, table, query, values, keys, model)
+	} else {
+		result, er1 := GetExecutor(ctx, db).ExecContext(ctx, query, values...)
+		if er1 != nil {
+			err = HandleError(db, er1)
+		} else {
+			count, err = result.RowsAffected()
+		}
+	}
+	if err == nil && count > 0 {
+		return count, nil
+	}
+	setVersion(model, versionIndex, currentVersion)
+	if err != nil {
+		return -1, err
+	}
+	return 0, checkVersion(ctx, db, table, versionColName, keys)
 }
 
 func Patch(ctx context.Context, db *sql.DB, table string, model map[string]interface{}, modelType reflect.Type, options ...func(i int) string) (int64, error) {
@@ -344,30 +501,41 @@ func Patch(ctx context.Context, db *sql.DB, table string, model map[string]inter
 	} else {
 		buildParam = GetBuild(db)
 	}
-	query, value := BuildPatch(table, model, columNames, idJsonName, idcolumNames, buildParam)
+	t, tenant, err := patchTenant(ctx, model, modelType)
+	if err != nil {
+		return -1, err
+	}
+	dialect := GetDialect(db)
+	query, value := BuildPatch(table, model, columNames, idJsonName, idcolumNames, buildParam, dialect)
 	if query == "" {
 		return 0, errors.New("fail to build query")
 	}
-	result, err := db.ExecContext(ctx, query, value...)
+	query, value = t.Where(dialect, query, value, tenant, buildParam)
+	result, err := GetExecutor(ctx, db).ExecContext(ctx, query, value...)
 	if err != nil {
-		return -1, err
+		return -1, HandleError(db, err)
 	}
 	return result.RowsAffected()
 }
 
+// PatchWithVersion returns a *VersionError of ErrNotFound or ErrVersionConflict if no row is updated, and then the version of model is not changed.
 func PatchWithVersion(ctx context.Context, db *sql.DB, table string, model map[string]interface{}, modelType reflect.Type, versionIndex int, options ...func(i int) string) (int64, error) {
 	if versionIndex < 0 {
 		return 0, errors.New("version's index not found")
 	}
-
-	idcolumNames, idJsonName := FindPrimaryKeys(modelType)
-	columNames := FindJsonName(modelType)
 	var buildParam func(i int) string
 	if len(options) > 0 && options[0] != nil {
 		buildParam = options[0]
 	} else {
 		buildParam = GetBuild(db)
 	}
+	return patchWithVersion(ctx, db, table, model, modelType, versionIndex, GetVersionStrategy(modelType.Field(versionIndex).Type), buildParam, nil)
+}
+
+// patchWithVersion fills result with the stored row if result is not nil.
+func patchWithVersion(ctx context.Context, db *sql.DB, table string, model map[string]interface{}, modelType reflect.Type, versionIndex int, strategy VersionStrategy, buildParam func(i int) string, result interface{}) (int64, error) {
+	idcolumNames, idJsonName := FindPrimaryKeys(modelType)
+	columNames := FindJsonName(modelType)
 	versionJsonName, ok := GetJsonNameByIndex(modelType, versionIndex)
 	if !ok {
 		return 0, errors.New("version's json not found")
@@ -376,16 +544,45 @@ func PatchWithVersion(ctx context.Context, db *sql.DB, table string, model map[s
 	if !ok {
 		return 0, errors.New("version's column not found")
 	}
-
-	query, value := BuildPatchWithVersion(table, model, columNames, idJsonName, idcolumNames, buildParam, versionIndex, versionJsonName, versionColName)
-	if query == "" {
-		return 0, errors.New("fail to build query")
+	currentVersion, ok := model[versionJsonName]
+	if !ok {
+		return 0, errors.New("version field not found")
 	}
-	result, err := db.ExecContext(ctx, query, value...)
+	currentVersion = toVersion(currentVersion, modelType.Field(versionIndex).Type)
+	model[versionJsonName] = currentVersion
+	t, tenant, err := patchTenant(ctx, model, modelType)
 	if err != nil {
 		return -1, err
 	}
-	return result.RowsAffected()
+	dialect := GetDialect(db)
+	query, value, err := buildPatchWithVersion(table, model, columNames, idJsonName, idcolumNames, buildParam, versionJsonName, versionColName, strategy, dialect)
+	if err != nil {
+		return -1, err
+	}
+	query, value = t.Where(dialect, query, value, tenant, buildParam)
+	keys := buildKeys(model, idJsonName, idcolumNames)
+	if t != nil {
+		keys[t.Column] = tenant
+	}
+	var count int64
+	if result != nil {
+		count, err = ExecReturning(ctx, db, table, query, value, keys, result)
+	} else {
+		r, er1 := GetExecutor(ctx, db).ExecContext(ctx, query, value...)
+		if er1 != nil {
+			err = HandleError(db, er1)
+		} else {
+			count, err = r.RowsAffected()
+		}
+	}
+	if err == nil && count > 0 {
+		return count, nil
+	}
+	model[versionJsonName] = currentVersion
+	if err != nil {
+		return -1, err
+	}
+	return 0, checkVersion(ctx, db, table, versionColName, keys)
 }
 
 func Delete(ctx context.Context, db *sql.DB, table string, query map[string]interface{}, options ...func(i int) string) (int64, error) {
@@ -395,12 +592,12 @@ func Delete(ctx context.Context, db *sql.DB, table string, query map[string]inte
 	} else {
 		buildParam = GetBuild(db)
 	}
-	sql, values := BuildDelete(table, query, buildParam)
+	sql, values := BuildDelete(table, query, buildParam, GetDialect(db))
 
-	result, err := db.ExecContext(ctx, sql, values...)
+	result, err := GetExecutor(ctx, db).ExecContext(ctx, sql, values...)
 
 	if err != nil {
-		return -1, err
+		return -1, HandleError(db, err)
 	}
 	return BuildResult(result.RowsAffected())
 }
@@ -431,7 +628,8 @@ func GetFieldByJson(modelType reflect.Type, jsonName string) (int, string, strin
 	return -1, jsonName, jsonName
 }
 
-func BuildUpdate(table string, model interface{}, i int, buildParam func(int) string) (string, []interface{}) {
+func BuildUpdate(table string, model interface{}, i int, buildParam func(int) string, options ...Dialect) (string, []interface{}) {
+	d := getDialect(options)
 	mapData, mapKey, columns, keys := BuildMapDataAndKeys(model, true)
 	var values []interface{}
 	colSet := make([]string, 0)
@@ -441,31 +639,31 @@ func BuildUpdate(table string, model interface{}, i int, buildParam func(int) st
 		if v1, ok := mapData[colName]; ok {
 			v3, ok3 := GetDBValue(v1)
 			if ok3 {
-				colSet = append(colSet, QuoteColumnName(colName)+"="+v3)
+				colSet = append(colSet, QuoteName(d, colName)+"="+v3)
 			} else {
 				values = append(values, v1)
-				colSet = append(colSet, QuoteColumnName(colName)+"="+buildParam(colNumber+i))
+				colSet = append(colSet, QuoteName(d, colName)+"="+buildParam(colNumber+i))
 				colNumber++
 			}
 		} else {
-			colSet = append(colSet, BuildParamWithNull(colName))
+			colSet = append(colSet, BuildParamWithNull(colName, d))
 		}
 	}
 	for _, colName := range keys {
 		if v2, ok := mapKey[colName]; ok {
 			v3, ok3 := GetDBValue(v2)
 			if ok3 {
-				colQuery = append(colQuery, QuoteColumnName(colName) + "=" + v3)
+				colQuery = append(colQuery, QuoteName(d, colName) + "=" + v3)
 			} else {
 				values = append(values, v2)
-				colQuery = append(colQuery, QuoteColumnName(colName)+"="+buildParam(colNumber+i))
+				colQuery = append(colQuery, QuoteName(d, colName)+"="+buildParam(colNumber+i))
+				colNumber++
 			}
-			colNumber++
 		}
 	}
 	queryWhere := strings.Join(colQuery, " and ")
 	querySet := strings.Join(colSet, ",")
-	query := fmt.Sprintf("update %v set %v where %v", table, querySet, queryWhere)
+	query := fmt.Sprintf("update %v set %v where %v", QuoteName(d, table), querySet, queryWhere)
 	return query, values
 }
 func GetDBValue(v interface{}) (string, bool) {
@@ -486,24 +684,32 @@ func GetDBValue(v interface{}) (string, bool) {
 		return "", false
 	}
 }
-func BuildUpdateWithVersion(table string, model interface{}, i int, versionIndex int, buildParam func(int) string) (string, []interface{}) {
+// BuildUpdateWithVersion sets the next version of model, and panics if it cannot be set; UpdateWithVersion returns the error instead.
+func BuildUpdateWithVersion(table string, model interface{}, i int, versionIndex int, buildParam func(int) string, options ...Dialect) (string, []interface{}) {
 	if versionIndex < 0 {
 		panic("version's index not found")
 	}
-	valueOfModel := reflect.Indirect(reflect.ValueOf(model))
-	currentVersion := reflect.Indirect(valueOfModel.Field(versionIndex)).Int()
-	nextVersion := currentVersion + 1
-	_, err := setValue(model, versionIndex, &nextVersion)
+	versionColName, exist := GetColumnNameByIndex(reflect.Indirect(reflect.ValueOf(model)).Type(), versionIndex)
+	if !exist {
+		panic("version's column not found")
+	}
+	query, values, err := buildUpdateWithVersion(table, model, i, versionIndex, versionColName, getVersionStrategy(model, versionIndex), buildParam, getDialect(options))
 	if err != nil {
 		panic(err)
 	}
+	return query, values
+}
+func buildUpdateWithVersion(table string, model interface{}, i int, versionIndex int, versionColName string, strategy VersionStrategy, buildParam func(int) string, d Dialect) (string, []interface{}, error) {
+	currentVersion := getVersion(model, versionIndex)
+	nextVersion, err := strategy.Next(currentVersion)
+	if err != nil {
+		return "", nil, err
+	}
+	if err = setVersion(model, versionIndex, nextVersion); err != nil {
+		return "", nil, err
+	}
 
 	mapData, mapKey, columns, keys := BuildMapDataAndKeys(model, true)
-	versionColName, exist := GetColumnNameByIndex(valueOfModel.Type(), versionIndex)
-	if !exist {
-		panic("version's column not found")
-	}
-	mapKey[versionColName] = currentVersion
 
 	var values []interface{}
 	colSet := make([]string, 0)
@@ -513,35 +719,42 @@ func BuildUpdateWithVersion(table string, model interface{}, i int, versionIndex
 		if v1, ok := mapData[colName]; ok {
 			v3, ok3 := GetDBValue(v1)
 			if ok3 {
-				colSet = append(colSet, fmt.Sprintf("%v = "+v3, colName))
+				colSet = append(colSet, QuoteName(d, colName)+"="+v3)
 			} else {
 				values = append(values, v1)
-				colQuery = append(colQuery, QuoteColumnName(colName) + "=" + buildParam(colNumber+i))
+				colSet = append(colSet, QuoteName(d, colName)+"="+buildParam(colNumber+i))
 				colNumber++
 			}
 		} else {
-			colSet = append(colSet, BuildParamWithNull(colName))
+			colSet = append(colSet, BuildParamWithNull(colName, d))
 		}
 	}
 	for _, colName := range keys {
 		if v2, ok := mapKey[colName]; ok {
 			v3, ok3 := GetDBValue(v2)
 			if ok3 {
-				colQuery = append(colQuery, QuoteColumnName(colName) + "=" + v3)
+				colQuery = append(colQuery, QuoteName(d, colName) + "=" + v3)
 			} else {
 				values = append(values, v2)
-				colQuery = append(colQuery, QuoteColumnName(colName) + "=" + buildParam(colNumber+i))
+				colQuery = append(colQuery, QuoteName(d, colName) + "=" + buildParam(colNumber+i))
+				colNumber++
 			}
-			colNumber++
 		}
 	}
+	if currentVersion == nil {
+		colQuery = append(colQuery, QuoteName(d, versionColName)+" is null")
+	} else {
+		values = append(values, currentVersion)
+		colQuery = append(colQuery, QuoteName(d, versionColName)+"="+buildParam(colNumber+i))
+	}
 	queryWhere := strings.Join(colQuery, " and ")
 	querySet := strings.Join(colSet, ",")
-	query := fmt.Sprintf("update %v set %v where %v", table, querySet, queryWhere)
-	return query, values
+	query := fmt.Sprintf("update %v set %v where %v", QuoteName(d, table), querySet, queryWhere)
+	return query, values, nil
 }
 
-func BuildPatch(table string, model map[string]interface{}, mapJsonColum map[string]string, idTagJsonNames []string, idColumNames []string, buildParam func(int) string) (string, []interface{}) {
+func BuildPatch(table string, model map[string]interface{}, mapJsonColum map[string]string, idTagJsonNames []string, idColumNames []string, buildParam func(int) string, options ...Dialect) (string, []interface{}) {
+	d := getDialect(options)
 	scope := statement()
 	// Append variables set column
 	for key, _ := range model {
@@ -560,35 +773,45 @@ func BuildPatch(table string, model map[string]interface{}, mapJsonColum map[str
 	var value []interface{}
 
 	n := len(scope.Columns)
-	sets, val1, err1 := BuildSqlParametersAndValues(scope.Columns, scope.Values, &n, 0, ", ", buildParam)
+	sets, val1, err1 := BuildSqlParametersAndValues(QuoteNames(d, scope.Columns), scope.Values, &n, 0, ", ", buildParam)
 	if err1 != nil {
 		return "", nil
 	}
 	value = append(value, val1...)
 	columnsKeys := len(scope.Keys)
-	where, val2, err2 := BuildSqlParametersAndValues(scope.Keys, scope.Values, &columnsKeys, n, " and ", buildParam)
+	where, val2, err2 := BuildSqlParametersAndValues(QuoteNames(d, scope.Keys), scope.Values, &columnsKeys, n, " and ", buildParam)
 	if err2 != nil {
 		return "", nil
 	}
 	value = append(value, val2...)
 	query := fmt.Sprintf("update %s set %s where %s",
-		table,
+		QuoteName(d, table),
 		sets,
 		where,
 	)
 	return query, value
 }
 
-func BuildPatchWithVersion(table string, model map[string]interface{}, mapJsonColum map[string]string, idTagJsonNames []string, idColumNames []string, buildParam func(int) string, versionIndex int, versionJsonName, versionColName string) (string, []interface{}) {
+// BuildPatchWithVersion sets the next version of model, and panics if it cannot be set; PatchWithVersion returns the error instead.
+func BuildPatchWithVersion(table string, model map[string]interface{}, mapJsonColum map[string]string, idTagJsonNames []string, idColumNames []string, buildParam func(int) string, versionIndex int, versionJsonName, versionColName string, options ...Dialect) (string, []interface{}) {
 	if versionIndex < 0 {
 		panic("version's index not found")
 	}
-
+	query, values, err := buildPatchWithVersion(table, model, mapJsonColum, idTagJsonNames, idColumNames, buildParam, versionJsonName, versionColName, IncrementVersion{}, getDialect(options))
+	if err != nil {
+		panic(err)
+	}
+	return query, values
+}
+func buildPatchWithVersion(table string, model map[string]interface{}, mapJsonColum map[string]string, idTagJsonNames []string, idColumNames []string, buildParam func(int) string, versionJsonName, versionColName string, strategy VersionStrategy, d Dialect) (string, []interface{}, error) {
 	currentVersion, ok := model[versionJsonName]
 	if !ok {
-		panic("version field not found")
+		return "", nil, errors.New("version field not found")
+	}
+	nextVersion, err := strategy.Next(currentVersion)
+	if err != nil {
+		return "", nil, err
 	}
-	nextVersion := currentVersion.(int64) + 1
 	model[versionJsonName] = nextVersion
 
 	scope := statement()
@@ -611,36 +834,37 @@ func BuildPatchWithVersion(table string, model map[string]interface{}, mapJsonCo
 	scope.Keys = append(scope.Keys, versionColName)
 
 	n := len(scope.Columns)
-	sets, setVal, err1 := BuildSqlParametersAndValues(scope.Columns, scope.Values, &n, 0, ", ", buildParam)
+	sets, setVal, err1 := BuildSqlParametersAndValues(QuoteNames(d, scope.Columns), scope.Values, &n, 0, ", ", buildParam)
 	if err1 != nil {
-		return "", nil
+		return "", nil, err1
 	}
 	value = append(value, setVal...)
 	numKeys := len(scope.Keys)
-	where, whereVal, err2 := BuildSqlParametersAndValues(scope.Keys, scope.Values, &numKeys, n, " and ", buildParam)
+	where, whereVal, err2 := BuildSqlParametersAndValues(QuoteNames(d, scope.Keys), scope.Values, &numKeys, n, " and ", buildParam)
 	if err2 != nil {
-		return "", nil
+		return "", nil, err2
 	}
 	value = append(value, whereVal...)
 	query := fmt.Sprintf("update %s set %s where %s",
-		table,
+		QuoteName(d, table),
 		sets,
 		where,
 	)
-	return query, value
+	return query, value, nil
 }
 
-func BuildDelete(table string, ids map[string]interface{}, buildParam func(int) string) (string, []interface{}) {
+func BuildDelete(table string, ids map[string]interface{}, buildParam func(int) string, options ...Dialect) (string, []interface{}) {
+	d := getDialect(options)
 	var values []interface{}
 	var queryArr []string
 	i := 1
 	for key, value := range ids {
-		queryArr = append(queryArr, fmt.Sprintf("%v = %v", QuoteColumnName(key), buildParam(i)))
+		queryArr = append(queryArr, fmt.Sprintf("%v = %v", QuoteName(d, key), buildParam(i)))
 		values = append(values, value)
 		i++
 	}
 	q := strings.Join(queryArr, " and ")
-	return fmt.Sprintf("delete from %v where %v", table, q), values
+	return fmt.Sprintf("delete from %v where %v", QuoteName(d, table), q), values
 }
 
 func ExtractBySchema(value interface{}, columns []string, schema map[string]FieldDB) (map[string]interface{}, map[string]interface{}, map[string]interface{}, error) {
@@ -1080,7 +1304,7 @@ func ReplaceQueryArgs(driver string, query string) string {
 	return query
 }
 func Exist(ctx context.Context, db *sql.DB, sql string, args ...interface{}) (bool, error) {
-	rows, err := db.QueryContext(ctx, sql, args...)
+	rows, err := GetExecutor(ctx, db).QueryContext(ctx, sql, args...)
 	if err != nil {
 		return false, err
 	}
@@ -1133,17 +1357,7 @@ func BuildDollarParam(i int) string {
 	return "$" + strconv.Itoa(i)
 }
 func GetBuild(db *sql.DB) func(i int) string {
-	driver := reflect.TypeOf(db.Driver()).String()
-	switch driver {
-	case "*pq.Driver":
-		return BuildDollarParam
-	case "*godror.drv":
-		return BuildOracleParam
-	case "*mssql.Driver":
-		return BuildMsSqlParam
-	default:
-		return BuildParam
-	}
+	return GetDialect(db).BuildParam
 }
 
 func MapToDB(model *map[string]interface{}, modelType reflect.Type) {
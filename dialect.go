@@ -0,0 +1,519 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	UpsertOnConflict      = "on conflict"
+	UpsertOnDuplicateKey  = "on duplicate key"
+	UpsertInsertOrReplace = "insert or replace"
+	UpsertMerge           = "merge"
+	UpsertMergeDual       = "merge dual"
+	// UpsertStatement is the "upsert into" statement of CockroachDB
+	UpsertStatement = "upsert"
+
+	KeyReturning      = "returning"
+	KeyLastInsertId   = "last insert id"
+	KeyOutputInserted = "output inserted"
+	KeyReturningInto  = "returning into"
+
+	DriverCockroach = "cockroach"
+	DriverMariaDB   = "mariadb"
+	DriverTiDB      = "tidb"
+)
+
+// Dialect is the SQL syntax of a database.
+type Dialect interface {
+	// Name returns the driver family, such as DriverPostgres. It is the value of GetDriver.
+	Name() string
+	BuildParam(i int) string
+	// Quote quotes one part of an identifier. Use QuoteIdentifier to quote a schema-qualified name.
+	Quote(name string) string
+	// BuildPaging returns the clause appended to a query to fetch limit rows from offset.
+	BuildPaging(limit int64, offset int64) string
+	// Upsert returns the upsert syntax, such as UpsertOnConflict, or an empty string if it is not supported.
+	Upsert() string
+	// GeneratedKey returns how an insert statement returns the generated key, such as KeyReturning, or an empty string if it is not supported.
+	GeneratedKey() string
+	IsDuplicate(err error) bool
+	Bool(b bool) string
+}
+
+// Returner is implemented by the dialects which support the "returning" clause.
+type Returner interface {
+	Returning(columns ...string) string
+}
+
+// WindowCounter is implemented by the dialects which support count(*) over(), to count the rows of a page in the query of the page.
+type WindowCounter interface {
+	WindowCount() bool
+}
+
+// CountEstimator is implemented by the dialects which can estimate the number of the rows of a query without running it.
+type CountEstimator interface {
+	EstimateCount(ctx context.Context, exec Executor, query string, params []interface{}) (int64, error)
+}
+
+// FullText is implemented by the dialects which support full-text search.
+type FullText interface {
+	// FullText returns the condition of the rows of which column matches the full-text query param.
+	FullText(column string, param string) string
+	// FullTextQuery returns the full-text query of the words of text, which must all be matched.
+	FullTextQuery(text string) string
+	// FullTextOrder returns the order of the rows by the relevance of column to the full-text query param, or an empty string if it is not supported.
+	FullTextOrder(column string, param string) string
+}
+
+// Variant is implemented by the dialects of the databases which are compatible with a driver family, such as CockroachDB with postgres.
+// The dialect is registered by its Variant, while Name is the driver family, which is the value of GetDriver.
+type Variant interface {
+	Variant() string
+}
+
+// TxRetrier is implemented by the dialects which need to retry transactions by default, if Config.TxRetry is not set.
+type TxRetrier interface {
+	TxRetry() BackoffConfig
+}
+
+type PostgresDialect struct{}
+
+func (d PostgresDialect) Name() string {
+	return DriverPostgres
+}
+func (d PostgresDialect) BuildParam(i int) string {
+	return BuildDollarParam(i)
+}
+func (d PostgresDialect) Quote(name string) string {
+	if IsPlainIdentifier(name) {
+		name = strings.ToLower(name)
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+func (d PostgresDialect) BuildPaging(limit int64, offset int64) string {
+	return fmt.Sprintf(" limit %d offset %d ", limit, offset)
+}
+func (d PostgresDialect) Upsert() string {
+	return UpsertOnConflict
+}
+func (d PostgresDialect) Returning(columns ...string) string {
+	return " returning " + strings.Join(columns, ",")
+}
+func (d PostgresDialect) GeneratedKey() string {
+	return KeyReturning
+}
+func (d PostgresDialect) WindowCount() bool {
+	return true
+}
+func (d PostgresDialect) FullText(column string, param string) string {
+	return "to_tsvector(" + column + ") @@ plainto_tsquery(" + param + ")"
+}
+func (d PostgresDialect) FullTextQuery(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+func (d PostgresDialect) FullTextOrder(column string, param string) string {
+	return "ts_rank(to_tsvector(" + column + "), plainto_tsquery(" + param + ")) desc"
+}
+
+// EstimateCount returns the number of the rows of query estimated by the planner, from the statistics of the tables, such as pg_class.reltuples.
+func (d PostgresDialect) EstimateCount(ctx context.Context, exec Executor, query string, params []interface{}) (int64, error) {
+	var plan string
+	if err := exec.QueryRowContext(ctx, "explain (format json) "+query, params...).Scan(&plan); err != nil {
+		return -1, err
+	}
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(plan), &plans); err != nil {
+		return -1, err
+	}
+	if len(plans) == 0 {
+		return -1, errors.New("no plan of query")
+	}
+	return int64(plans[0].Plan.Rows), nil
+}
+func (d PostgresDialect) IsDuplicate(err error) bool {
+	return errors.Is(ClassifyError(DriverPostgres, err), ErrDuplicateKey)
+}
+func (d PostgresDialect) Bool(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+type MySQLDialect struct{}
+
+func (d MySQLDialect) Name() string {
+	return DriverMysql
+}
+func (d MySQLDialect) BuildParam(i int) string {
+	return BuildParam(i)
+}
+func (d MySQLDialect) Quote(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+func (d MySQLDialect) BuildPaging(limit int64, offset int64) string {
+	return fmt.Sprintf(" limit %d offset %d ", limit, offset)
+}
+func (d MySQLDialect) Upsert() string {
+	return UpsertOnDuplicateKey
+}
+func (d MySQLDialect) GeneratedKey() string {
+	return KeyLastInsertId
+}
+
+// WindowCount requires MySQL 8 or MariaDB 10.2.
+func (d MySQLDialect) WindowCount() bool {
+	return true
+}
+
+// FullText requires a fulltext index of column.
+func (d MySQLDialect) FullText(column string, param string) string {
+	return "match(" + column + ") against (" + param + " in boolean mode)"
+}
+func (d MySQLDialect) FullTextQuery(text string) string {
+	return quoteWords(text, "+\"", "\"", " ", "")
+}
+func (d MySQLDialect) FullTextOrder(column string, param string) string {
+	return "match(" + column + ") against (" + param + " in boolean mode) desc"
+}
+func (d MySQLDialect) IsDuplicate(err error) bool {
+	return errors.Is(ClassifyError(DriverMysql, err), ErrDuplicateKey)
+}
+func (d MySQLDialect) Bool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// CockroachDialect is the postgres dialect, with the upsert statement.
+// CockroachDB runs transactions in serializable isolation, so they are retried by default on serialization failure.
+type CockroachDialect struct {
+	PostgresDialect
+}
+
+func (d CockroachDialect) Variant() string {
+	return DriverCockroach
+}
+func (d CockroachDialect) Upsert() string {
+	return UpsertStatement
+}
+func (d CockroachDialect) TxRetry() BackoffConfig {
+	return BackoffConfig{MaxAttempts: 5, InitialInterval: 50, MaxInterval: 2000, Multiplier: 2, Jitter: 0.5}
+}
+
+// EstimateCount is not supported, because the plan of CockroachDB is not in the format of postgres, so the rows are counted.
+func (d CockroachDialect) EstimateCount(ctx context.Context, exec Executor, query string, params []interface{}) (int64, error) {
+	return -1, errors.New("estimated count is not supported by cockroach")
+}
+
+// MariaDBDialect is the mysql dialect, with "insert ... returning" of MariaDB 10.5.
+// The update statements of MariaDB have no "returning" clause, so ExecReturning selects the updated row.
+type MariaDBDialect struct {
+	MySQLDialect
+}
+
+func (d MariaDBDialect) Variant() string {
+	return DriverMariaDB
+}
+func (d MariaDBDialect) GeneratedKey() string {
+	return KeyReturning
+}
+func (d MariaDBDialect) Returning(columns ...string) string {
+	return " returning " + strings.Join(columns, ",")
+}
+func (d MariaDBDialect) UpdateReturning() bool {
+	return false
+}
+
+// TiDBDialect is the mysql dialect of TiDB, of which the optimistic transactions are retried by default on write conflict.
+type TiDBDialect struct {
+	MySQLDialect
+}
+
+func (d TiDBDialect) Variant() string {
+	return DriverTiDB
+}
+func (d TiDBDialect) TxRetry() BackoffConfig {
+	return BackoffConfig{MaxAttempts: 5, InitialInterval: 50, MaxInterval: 2000, Multiplier: 2, Jitter: 0.5}
+}
+
+// updateReturning returns false for the dialects of which the "returning" clause is supported by the insert statements only, such as MariaDB.
+func updateReturning(d Dialect) bool {
+	u, ok := d.(interface{ UpdateReturning() bool })
+	return !ok || u.UpdateReturning()
+}
+
+type MssqlDialect struct{}
+
+func (d MssqlDialect) Name() string {
+	return DriverMssql
+}
+func (d MssqlDialect) BuildParam(i int) string {
+	return BuildMsSqlParam(i)
+}
+func (d MssqlDialect) Quote(name string) string {
+	return "[" + strings.Replace(name, "]", "]]", -1) + "]"
+}
+
+// BuildPaging requires an order by clause in the query.
+func (d MssqlDialect) BuildPaging(limit int64, offset int64) string {
+	return fmt.Sprintf(" offset %d rows fetch next %d rows only ", offset, limit)
+}
+func (d MssqlDialect) Upsert() string {
+	return UpsertMerge
+}
+func (d MssqlDialect) GeneratedKey() string {
+	return KeyOutputInserted
+}
+func (d MssqlDialect) WindowCount() bool {
+	return true
+}
+
+// FullText requires a full-text index of column. The rows cannot be ordered by relevance without containstable.
+func (d MssqlDialect) FullText(column string, param string) string {
+	return "contains(" + column + ", " + param + ")"
+}
+func (d MssqlDialect) FullTextQuery(text string) string {
+	return quoteWords(text, "\"", "\"", " and ", `""`)
+}
+func (d MssqlDialect) FullTextOrder(column string, param string) string {
+	return ""
+}
+func (d MssqlDialect) IsDuplicate(err error) bool {
+	return errors.Is(ClassifyError(DriverMssql, err), ErrDuplicateKey)
+}
+func (d MssqlDialect) Bool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+type OracleDialect struct{}
+
+func (d OracleDialect) Name() string {
+	return DriverOracle
+}
+func (d OracleDialect) BuildParam(i int) string {
+	return BuildOracleParam(i)
+}
+func (d OracleDialect) Quote(name string) string {
+	if IsPlainIdentifier(name) {
+		name = strings.ToUpper(name)
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+func (d OracleDialect) BuildPaging(limit int64, offset int64) string {
+	return fmt.Sprintf(OraclePagingFormat, fmt.Sprint(offset), fmt.Sprint(limit))
+}
+func (d OracleDialect) Upsert() string {
+	return UpsertMergeDual
+}
+func (d OracleDialect) GeneratedKey() string {
+	return KeyReturningInto
+}
+func (d OracleDialect) WindowCount() bool {
+	return true
+}
+
+// FullText requires an Oracle Text index of column. The rows are not ordered by relevance, because the label of score must be unique in a query.
+func (d OracleDialect) FullText(column string, param string) string {
+	return "contains(" + column + ", " + param + ") > 0"
+}
+func (d OracleDialect) FullTextQuery(text string) string {
+	return quoteWords(text, "{", "}", " and ", "}}")
+}
+func (d OracleDialect) FullTextOrder(column string, param string) string {
+	return ""
+}
+func (d OracleDialect) IsDuplicate(err error) bool {
+	return errors.Is(ClassifyError(DriverOracle, err), ErrDuplicateKey)
+}
+func (d OracleDialect) Bool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+type SqliteDialect struct{}
+
+func (d SqliteDialect) Name() string {
+	return DriverSqlite3
+}
+func (d SqliteDialect) BuildParam(i int) string {
+	return BuildParam(i)
+}
+func (d SqliteDialect) Quote(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+func (d SqliteDialect) BuildPaging(limit int64, offset int64) string {
+	return fmt.Sprintf(" limit %d offset %d ", limit, offset)
+}
+func (d SqliteDialect) Upsert() string {
+	return UpsertInsertOrReplace
+}
+func (d SqliteDialect) GeneratedKey() string {
+	return KeyReturning
+}
+
+// WindowCount requires SQLite 3.25.
+func (d SqliteDialect) WindowCount() bool {
+	return true
+}
+
+// FullText requires column to be a column of an FTS5 table.
+func (d SqliteDialect) FullText(column string, param string) string {
+	return column + " match " + param
+}
+func (d SqliteDialect) FullTextQuery(text string) string {
+	return quoteWords(text, "\"", "\"", " ", `""`)
+}
+func (d SqliteDialect) FullTextOrder(column string, param string) string {
+	return "rank"
+}
+func (d SqliteDialect) Returning(columns ...string) string {
+	return " returning " + strings.Join(columns, ",")
+}
+func (d SqliteDialect) IsDuplicate(err error) bool {
+	return errors.Is(ClassifyError(DriverSqlite3, err), ErrDuplicateKey)
+}
+func (d SqliteDialect) Bool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// quoteWords returns the words of text, each between open and end, in which end is replaced by escape, joined by sep.
+func quoteWords(text string, open string, end string, sep string, escape string) string {
+	words := strings.Fields(text)
+	for i, word := range words {
+		words[i] = open + strings.Replace(word, end, escape, -1) + end
+	}
+	return strings.Join(words, sep)
+}
+
+// DefaultDialect is used for the drivers which are not registered.
+type DefaultDialect struct{}
+
+func (d DefaultDialect) Name() string {
+	return DriverNotSupport
+}
+func (d DefaultDialect) BuildParam(i int) string {
+	return BuildParam(i)
+}
+func (d DefaultDialect) Quote(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+func (d DefaultDialect) BuildPaging(limit int64, offset int64) string {
+	return fmt.Sprintf(" limit %d offset %d ", limit, offset)
+}
+func (d DefaultDialect) Upsert() string {
+	return ""
+}
+func (d DefaultDialect) GeneratedKey() string {
+	return ""
+}
+func (d DefaultDialect) IsDuplicate(err error) bool {
+	return errors.Is(ClassifyError(DriverNotSupport, err), ErrDuplicateKey)
+}
+func (d DefaultDialect) Bool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+var (
+	dialectMu sync.RWMutex
+	// dialects are registered by name, by database/sql driver name and by reflect type name of the driver, such as "*pq.Driver"
+	dialects = map[string]Dialect{
+		DriverPostgres: PostgresDialect{},
+		"pgx":          PostgresDialect{},
+		"*pq.Driver":   PostgresDialect{},
+		// github.com/jackc/pgx/v4/stdlib and v5/stdlib
+		"*stdlib.Driver": PostgresDialect{},
+
+		DriverCockroach: CockroachDialect{},
+		"cockroachdb":   CockroachDialect{},
+
+		DriverMysql:          MySQLDialect{},
+		"*mysql.MySQLDriver": MySQLDialect{},
+		DriverMariaDB:        MariaDBDialect{},
+		DriverTiDB:           TiDBDialect{},
+
+		// github.com/denisenkom/go-mssqldb and github.com/microsoft/go-mssqldb
+		DriverMssql:     MssqlDialect{},
+		"sqlserver":     MssqlDialect{},
+		"*mssql.Driver": MssqlDialect{},
+
+		// github.com/godror/godror and github.com/sijms/go-ora
+		DriverOracle:           OracleDialect{},
+		"godror":               OracleDialect{},
+		"*godror.drv":          OracleDialect{},
+		"*go_ora.OracleDriver": OracleDialect{},
+
+		// github.com/mattn/go-sqlite3 and modernc.org/sqlite
+		DriverSqlite3:           SqliteDialect{},
+		"sqlite":                SqliteDialect{},
+		"*sqlite3.SQLiteDriver": SqliteDialect{},
+		"*sqlite.Driver":        SqliteDialect{},
+	}
+	dbDialects sync.Map
+)
+
+// RegisterDialect registers d by its variant, or else by its name, and by the given names, which can be database/sql driver names or reflect type names of drivers.
+func RegisterDialect(d Dialect, names ...string) {
+	name := d.Name()
+	if v, ok := d.(Variant); ok {
+		name = v.Variant()
+	}
+	dialectMu.Lock()
+	defer dialectMu.Unlock()
+	dialects[name] = d
+	for _, name := range names {
+		dialects[name] = d
+	}
+}
+func GetDialectByName(name string) (Dialect, bool) {
+	dialectMu.RLock()
+	defer dialectMu.RUnlock()
+	d, ok := dialects[name]
+	return d, ok
+}
+
+// SetDialect sets the dialect of db explicitly, or removes it if d is nil.
+func SetDialect(db *sql.DB, d Dialect) {
+	if d == nil {
+		dbDialects.Delete(db)
+	} else {
+		dbDialects.Store(db, d)
+	}
+}
+
+// GetDialect returns the dialect set by SetDialect, or else the dialect registered for the type of the driver of db, or else DefaultDialect.
+func GetDialect(db *sql.DB) Dialect {
+	if db == nil {
+		return DefaultDialect{}
+	}
+	if d, ok := dbDialects.Load(db); ok {
+		return d.(Dialect)
+	}
+	if d, ok := GetDialectByName(reflect.TypeOf(db.Driver()).String()); ok {
+		return d
+	}
+	return DefaultDialect{}
+}
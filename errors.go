@@ -0,0 +1,337 @@
+package sql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrDuplicateKey         = errors.New("duplicate key")
+	ErrForeignKeyViolation  = errors.New("foreign key violation")
+	ErrNotNullViolation     = errors.New("not null violation")
+	ErrCheckViolation       = errors.New("check violation")
+	ErrDeadlock             = errors.New("deadlock")
+	ErrSerializationFailure = errors.New("serialization failure")
+	ErrLockTimeout          = errors.New("lock timeout")
+	ErrConnection           = errors.New("connection error")
+
+	ErrNotFound        = errors.New("not found")
+	ErrVersionConflict = errors.New("version conflict")
+	ErrTenantRequired  = errors.New("tenant not found in context")
+	ErrInvalidCursor   = errors.New("invalid cursor")
+	ErrInvalidFilter   = errors.New("invalid filter")
+	ErrKeysetQuery     = errors.New("query cannot be paged by keyset")
+)
+
+// VersionError is returned by the versioned writes which update no row.
+// Kind is ErrNotFound if the row does not exist, or ErrVersionConflict with the current Version of the row in the database.
+type VersionError struct {
+	Kind    error
+	Version interface{}
+}
+
+func (e *VersionError) Error() string {
+	if e.Kind == ErrVersionConflict {
+		return fmt.Sprintf("version conflict: current version is %v", e.Version)
+	}
+	return e.Kind.Error()
+}
+func (e *VersionError) Is(target error) bool {
+	return e.Kind == target
+}
+
+// DBError is a driver error classified into one of the sentinel errors above.
+// errors.Is(err, ErrDuplicateKey) is true for a DBError of kind ErrDuplicateKey, and errors.As still reaches the driver error.
+type DBError struct {
+	Kind       error
+	Code       string
+	Constraint string
+	Column     string
+	Err        error
+}
+
+func (e *DBError) Error() string {
+	return e.Err.Error()
+}
+func (e *DBError) Is(target error) bool {
+	return e.Kind == target
+}
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+// HandleError classifies err by the driver of db.
+func HandleError(db *sql.DB, err error) error {
+	return ClassifyError(GetDriver(db), err)
+}
+
+// ClassifyError returns a *DBError if err is a known database error of the driver, otherwise it returns err.
+func ClassifyError(driverName string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *DBError
+	if errors.As(err, &e) {
+		return err
+	}
+	if errors.Is(err, driver.ErrBadConn) {
+		return &DBError{Kind: ErrConnection, Err: err}
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return &DBError{Kind: ErrConnection, Err: err}
+	}
+	code := getErrorCode(err)
+	msg := err.Error()
+	var kind error
+	switch driverName {
+	case DriverPostgres:
+		kind = classifyPostgres(code)
+	case DriverMysql:
+		kind = classifyMysql(code)
+	case DriverMssql:
+		kind = classifyMssql(code, msg)
+	case DriverOracle:
+		kind = classifyOracle(code, msg)
+	case DriverSqlite3:
+		kind = classifySqlite(code, msg)
+	}
+	if kind == nil {
+		kind = classifyMessage(msg)
+	}
+	if kind == nil {
+		return err
+	}
+	de := &DBError{Kind: kind, Code: code, Err: err}
+	de.Constraint, de.Column = getConstraint(err, msg)
+	return de
+}
+
+func classifyPostgres(code string) error {
+	switch code {
+	case "23505":
+		return ErrDuplicateKey
+	case "23503":
+		return ErrForeignKeyViolation
+	case "23502":
+		return ErrNotNullViolation
+	case "23514":
+		return ErrCheckViolation
+	case "40P01":
+		return ErrDeadlock
+	case "40001":
+		return ErrSerializationFailure
+	case "55P03":
+		return ErrLockTimeout
+	case "57P01", "57P02", "57P03":
+		return ErrConnection
+	}
+	if strings.HasPrefix(code, "08") {
+		return ErrConnection
+	}
+	return nil
+}
+func classifyMysql(code string) error {
+	switch code {
+	case "1062", "1586":
+		return ErrDuplicateKey
+	case "1216", "1217", "1451", "1452":
+		return ErrForeignKeyViolation
+	case "1048", "1364":
+		return ErrNotNullViolation
+	case "3819":
+		return ErrCheckViolation
+	case "1213":
+		return ErrDeadlock
+	case "1020":
+		// "Record has changed since last read" of MariaDB with innodb_snapshot_isolation
+		return ErrSerializationFailure
+	case "8002", "8022", "8028", "9007":
+		// the write conflicts of TiDB, and the transactions which TiDB reports as safe to retry
+		return ErrSerializationFailure
+	case "1205":
+		return ErrLockTimeout
+	case "1040", "1053", "2002", "2003", "2006", "2013":
+		return ErrConnection
+	}
+	return nil
+}
+func classifyMssql(code string, msg string) error {
+	switch code {
+	case "2601", "2627":
+		return ErrDuplicateKey
+	case "547":
+		if strings.Contains(msg, "CHECK constraint") {
+			return ErrCheckViolation
+		}
+		return ErrForeignKeyViolation
+	case "515":
+		return ErrNotNullViolation
+	case "1205":
+		return ErrDeadlock
+	case "3960":
+		return ErrSerializationFailure
+	case "1222":
+		return ErrLockTimeout
+	}
+	return nil
+}
+func classifyOracle(code string, msg string) error {
+	if len(code) == 0 {
+		if m := oracleCode.FindStringSubmatch(msg); m != nil {
+			code = strings.TrimLeft(m[1], "0")
+		}
+	}
+	switch code {
+	case "1":
+		return ErrDuplicateKey
+	case "2291", "2292":
+		return ErrForeignKeyViolation
+	case "1400", "1407":
+		return ErrNotNullViolation
+	case "2290":
+		return ErrCheckViolation
+	case "60":
+		return ErrDeadlock
+	case "8177":
+		return ErrSerializationFailure
+	case "54", "30006":
+		return ErrLockTimeout
+	case "3113", "3114", "3135", "12170", "12541", "12543":
+		return ErrConnection
+	}
+	return nil
+}
+func classifySqlite(code string, msg string) error {
+	switch code {
+	case "1555", "2067":
+		return ErrDuplicateKey
+	case "787":
+		return ErrForeignKeyViolation
+	case "1299":
+		return ErrNotNullViolation
+	case "275":
+		return ErrCheckViolation
+	case "5", "6":
+		return ErrLockTimeout
+	}
+	return nil
+}
+func classifyMessage(msg string) error {
+	switch {
+	case strings.Contains(msg, "duplicate key value violates unique constraint"),
+		strings.Contains(msg, "Duplicate entry"),
+		strings.Contains(msg, "ORA-00001: unique constraint"),
+		strings.Contains(msg, "Violation of PRIMARY KEY constraint"),
+		strings.Contains(msg, "Violation of UNIQUE KEY constraint"),
+		strings.Contains(msg, "UNIQUE constraint failed"):
+		return ErrDuplicateKey
+	case strings.Contains(msg, "violates foreign key constraint"),
+		strings.Contains(msg, "a foreign key constraint fails"),
+		strings.Contains(msg, "FOREIGN KEY constraint"):
+		return ErrForeignKeyViolation
+	case strings.Contains(msg, "violates not-null constraint"),
+		strings.Contains(msg, "NOT NULL constraint failed"),
+		strings.Contains(msg, "cannot be null"):
+		return ErrNotNullViolation
+	case strings.Contains(msg, "violates check constraint"),
+		strings.Contains(msg, "CHECK constraint failed"):
+		return ErrCheckViolation
+	case strings.Contains(msg, "deadlock"), strings.Contains(msg, "Deadlock"):
+		return ErrDeadlock
+	case strings.Contains(msg, "could not serialize access"):
+		return ErrSerializationFailure
+	}
+	return nil
+}
+
+var (
+	oracleCode     = regexp.MustCompile(`ORA-(\d{5})`)
+	quotedName     = regexp.MustCompile(`(?:constraint|key) ['"]([^'"]+)['"]`)
+	oracleName     = regexp.MustCompile(`\(([^)]+)\) violated`)
+	sqliteColumn   = regexp.MustCompile(`constraint failed: ([\w.]+)`)
+	postgresColumn = regexp.MustCompile(`column "([^"]+)"`)
+)
+
+// getErrorCode reads the error code of the known drivers without importing them:
+// SQLState() or Code of pq and pgx, Number of mysql and mssql, Code() of godror, ExtendedCode or Code of sqlite.
+func getErrorCode(err error) string {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if s, ok := e.(interface{ SQLState() string }); ok {
+			return s.SQLState()
+		}
+		if s, ok := e.(interface{ SQLErrorNumber() int32 }); ok {
+			return strconv.Itoa(int(s.SQLErrorNumber()))
+		}
+		if s, ok := e.(interface{ Code() int }); ok {
+			return strconv.Itoa(s.Code())
+		}
+		v := reflect.Indirect(reflect.ValueOf(e))
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		for _, name := range []string{"ExtendedCode", "Number", "Code"} {
+			f := v.FieldByName(name)
+			if !f.IsValid() {
+				continue
+			}
+			switch f.Kind() {
+			case reflect.String:
+				return f.String()
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				if f.Int() != 0 {
+					return strconv.FormatInt(f.Int(), 10)
+				}
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				if f.Uint() != 0 {
+					return strconv.FormatUint(f.Uint(), 10)
+				}
+			}
+		}
+	}
+	return ""
+}
+func getConstraint(err error, msg string) (string, string) {
+	var constraint, column string
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		v := reflect.Indirect(reflect.ValueOf(e))
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		constraint = getStringField(v, "Constraint", "ConstraintName")
+		column = getStringField(v, "Column", "ColumnName")
+		if len(constraint) > 0 || len(column) > 0 {
+			return constraint, column
+		}
+	}
+	if m := oracleName.FindStringSubmatch(msg); m != nil {
+		constraint = m[1]
+	} else if m := sqliteColumn.FindStringSubmatch(msg); m != nil {
+		column = m[1]
+		if i := strings.LastIndex(column, "."); i >= 0 {
+			column = column[i+1:]
+		}
+	} else if m := quotedName.FindStringSubmatch(msg); m != nil {
+		constraint = m[1]
+	}
+	if m := postgresColumn.FindStringSubmatch(msg); m != nil {
+		column = m[1]
+	}
+	return constraint, column
+}
+func getStringField(v reflect.Value, names ...string) string {
+	for _, name := range names {
+		f := v.FieldByName(name)
+		if f.IsValid() && f.Kind() == reflect.String {
+			return f.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package sql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+// pqError, mysqlError, mssqlError and sqliteError have the fields of the errors of the drivers, which are read by reflection.
+type pqError struct {
+	Code       string
+	Message    string
+	Constraint string
+	Column     string
+}
+
+func (e *pqError) Error() string { return "pq: " + e.Message }
+
+type mysqlError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *mysqlError) Error() string { return fmt.Sprintf("Error %d: %s", e.Number, e.Message) }
+
+type mssqlError struct {
+	Number  int32
+	Message string
+}
+
+func (e mssqlError) Error() string { return "mssql: " + e.Message }
+
+type sqliteError struct {
+	Code         int
+	ExtendedCode int
+	msg          string
+}
+
+func (e sqliteError) Error() string { return e.msg }
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name       string
+		driver     string
+		err        error
+		kind       error
+		code       string
+		constraint string
+		column     string
+	}{
+		{"postgres duplicate", DriverPostgres, &pqError{Code: "23505", Message: `duplicate key value violates unique constraint "users_email_key"`, Constraint: "users_email_key"}, ErrDuplicateKey, "23505", "users_email_key", ""},
+		{"postgres not null", DriverPostgres, &pqError{Code: "23502", Message: `null value in column "email" violates not-null constraint`, Column: "email"}, ErrNotNullViolation, "23502", "", "email"},
+		{"postgres foreign key", DriverPostgres, &pqError{Code: "23503"}, ErrForeignKeyViolation, "23503", "", ""},
+		{"postgres check", DriverPostgres, &pqError{Code: "23514"}, ErrCheckViolation, "23514", "", ""},
+		{"postgres deadlock", DriverPostgres, &pqError{Code: "40P01"}, ErrDeadlock, "40P01", "", ""},
+		{"postgres serialization", DriverPostgres, &pqError{Code: "40001"}, ErrSerializationFailure, "40001", "", ""},
+		{"postgres lock timeout", DriverPostgres, &pqError{Code: "55P03"}, ErrLockTimeout, "55P03", "", ""},
+		{"postgres connection class", DriverPostgres, &pqError{Code: "08006"}, ErrConnection, "08006", "", ""},
+		{"mysql duplicate", DriverMysql, &mysqlError{Number: 1062, Message: "Duplicate entry 'a' for key 'users.email'"}, ErrDuplicateKey, "1062", "users.email", ""},
+		{"mysql deadlock", DriverMysql, &mysqlError{Number: 1213}, ErrDeadlock, "1213", "", ""},
+		{"mysql lock timeout", DriverMysql, &mysqlError{Number: 1205}, ErrLockTimeout, "1205", "", ""},
+		{"mysql foreign key", DriverMysql, &mysqlError{Number: 1452}, ErrForeignKeyViolation, "1452", "", ""},
+		{"mssql duplicate", DriverMssql, mssqlError{Number: 2627, Message: "Violation of PRIMARY KEY constraint 'PK_users'"}, ErrDuplicateKey, "2627", "PK_users", ""},
+		{"mssql check", DriverMssql, mssqlError{Number: 547, Message: "The INSERT statement conflicted with the CHECK constraint"}, ErrCheckViolation, "547", "", ""},
+		{"mssql foreign key", DriverMssql, mssqlError{Number: 547, Message: "The INSERT statement conflicted with the FOREIGN KEY constraint"}, ErrForeignKeyViolation, "547", "", ""},
+		{"oracle duplicate by message", DriverOracle, errors.New("ORA-00001: unique constraint (APP.USERS_PK) violated"), ErrDuplicateKey, "", "APP.USERS_PK", ""},
+		{"oracle deadlock by message", DriverOracle, errors.New("ORA-00060: deadlock detected while waiting for resource"), ErrDeadlock, "", "", ""},
+		{"sqlite unique", DriverSqlite3, sqliteError{Code: 19, ExtendedCode: 2067, msg: "UNIQUE constraint failed: users.email"}, ErrDuplicateKey, "2067", "", "email"},
+		{"sqlite busy", DriverSqlite3, sqliteError{Code: 5, ExtendedCode: 5, msg: "database is locked"}, ErrLockTimeout, "5", "", ""},
+		{"unknown driver by message", DriverNotSupport, errors.New("UNIQUE constraint failed: users.id"), ErrDuplicateKey, "", "", "id"},
+		{"wrapped", DriverPostgres, fmt.Errorf("insert user: %w", &pqError{Code: "23505"}), ErrDuplicateKey, "23505", "", ""},
+		{"bad connection", DriverPostgres, driver.ErrBadConn, ErrConnection, "", "", ""},
+		{"network", DriverMysql, &net.OpError{Op: "dial", Err: errors.New("connection refused")}, ErrConnection, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ClassifyError(tt.driver, tt.err)
+			if !errors.Is(err, tt.kind) {
+				t.Fatalf("ClassifyError() = %v, want kind %v", err, tt.kind)
+			}
+			var e *DBError
+			if !errors.As(err, &e) {
+				t.Fatalf("ClassifyError() = %T, want *DBError", err)
+			}
+			if e.Code != tt.code || e.Constraint != tt.constraint || e.Column != tt.column {
+				t.Errorf("ClassifyError() = {Code: %q, Constraint: %q, Column: %q}, want {%q, %q, %q}", e.Code, e.Constraint, e.Column, tt.code, tt.constraint, tt.column)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("ClassifyError() does not wrap %v", tt.err)
+			}
+		})
+	}
+}
+
+func TestClassifyErrorUnknown(t *testing.T) {
+	if err := ClassifyError(DriverPostgres, nil); err != nil {
+		t.Errorf("ClassifyError(nil) = %v, want nil", err)
+	}
+	unknown := &pqError{Code: "42601", Message: "syntax error"}
+	if err := ClassifyError(DriverPostgres, unknown); err != unknown {
+		t.Errorf("ClassifyError() = %v, want the error itself", err)
+	}
+	classified := &DBError{Kind: ErrDeadlock, Err: unknown}
+	if err := ClassifyError(DriverMysql, classified); err != classified {
+		t.Errorf("ClassifyError() = %v, want the classified error itself", err)
+	}
+}
@@ -10,6 +10,7 @@ type Inserter struct {
 	tableName  string
 	BuildParam func(i int) string
 	Map        func(ctx context.Context, model interface{}) (interface{}, error)
+	Audit      *Audit
 }
 func NewInserter(db *sql.DB, tableName string, options ...func(context.Context, interface{}) (interface{}, error)) *Inserter {
 	var mp func(context.Context, interface{}) (interface{}, error)
@@ -25,20 +26,28 @@ func NewSqlInserter(db *sql.DB, tableName string, mp func(context.Context, inter
 	} else {
 		buildParam = GetBuild(db)
 	}
-	return &Inserter{db: db, tableName: tableName, BuildParam: buildParam, Map: mp}
+	return &Inserter{db: db, tableName: tableName, BuildParam: buildParam, Map: mp, Audit: NewAudit(GetAuditUser(db))}
 }
 
 func (w *Inserter) Write(ctx context.Context, model interface{}) error {
+	w.Audit.OnInsert(ctx, model)
+	dialect := GetDialect(w.db)
 	if w.Map != nil {
 		m2, er0 := w.Map(ctx, model)
 		if er0 != nil {
 			return er0
 		}
-		queryInsert, values := BuildInsert(w.tableName, m2, 0, w.BuildParam)
-		_, err := w.db.ExecContext(ctx, queryInsert, values...)
-		return err
+		if er1 := SetTenant(ctx, m2); er1 != nil {
+			return er1
+		}
+		queryInsert, values := BuildInsert(w.tableName, m2, 1, w.BuildParam, dialect)
+		_, err := ExecInsert(ctx, GetExecutor(ctx, w.db), dialect, m2, queryInsert, values...)
+		return HandleError(w.db, err)
+	}
+	if er1 := SetTenant(ctx, model); er1 != nil {
+		return er1
 	}
-	queryInsert, values := BuildInsert(w.tableName, model, 0, w.BuildParam)
-	_, err := w.db.ExecContext(ctx, queryInsert, values...)
-	return err
+	queryInsert, values := BuildInsert(w.tableName, model, 1, w.BuildParam, dialect)
+	_, err := ExecInsert(ctx, GetExecutor(ctx, w.db), dialect, model, queryInsert, values...)
+	return HandleError(w.db, err)
 }
@@ -0,0 +1,290 @@
+package sql
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Cursor is the keyset paging of a search model, which has a field of type *Cursor: `json:"next,omitempty"`.
+// If the field is not nil, the page starts after the row of the cursor, or at the first row if the cursor is empty,
+// and SearchBuilder.Search sets it to the cursor of the next page, or to an empty cursor after the last page.
+// The sort columns should not be null, because a null value cannot be compared.
+type Cursor string
+
+// SortKey is a column of the sort order of the keyset paging.
+type SortKey struct {
+	Index  int
+	Column string
+	Json   string
+	Desc   bool
+}
+
+// BuildSortKeys returns the sort order of sortString, such as "-createdAt,name", followed by the primary keys of modelType which are not sorted yet,
+// so that the rows with the same sort values are always in the same order. If modelType has no primary_key tag, the id column is the primary key.
+// The fields which are not columns of modelType are ignored.
+func BuildSortKeys(sortString string, modelType reflect.Type) []SortKey {
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	keys := make([]SortKey, 0)
+	sorted := make(map[int]bool)
+	for _, s := range strings.Split(sortString, ",") {
+		sortField := strings.TrimSpace(s)
+		if len(sortField) == 0 {
+			continue
+		}
+		c := sortField[0:1]
+		if c == "-" || c == "+" {
+			sortField = sortField[1:]
+		}
+		i, _, column := GetFieldByJson(modelType, sortField)
+		if i < 0 || len(column) == 0 || sorted[i] {
+			continue
+		}
+		sorted[i] = true
+		keys = append(keys, SortKey{Index: i, Column: column, Json: sortField, Desc: c == "-"})
+	}
+	for _, i := range primaryKeyIndexes(modelType) {
+		if sorted[i] {
+			continue
+		}
+		column, ok := GetColumnNameByIndex(modelType, i)
+		if !ok {
+			continue
+		}
+		jsonName, ok := GetJsonNameByIndex(modelType, i)
+		if !ok || len(jsonName) == 0 {
+			jsonName = modelType.Field(i).Name
+		}
+		keys = append(keys, SortKey{Index: i, Column: column, Json: jsonName})
+	}
+	return keys
+}
+
+// primaryKeyIndexes returns the indexes of the fields of modelType with the primary_key tag, or else of the field of which the column or the json name is id.
+func primaryKeyIndexes(modelType reflect.Type) []int {
+	indexes := make([]int, 0)
+	numField := modelType.NumField()
+	for i := 0; i < numField; i++ {
+		if isPrimaryKey(modelType.Field(i)) {
+			indexes = append(indexes, i)
+		}
+	}
+	if len(indexes) > 0 {
+		return indexes
+	}
+	for i := 0; i < numField; i++ {
+		column, ok := GetColumnNameByIndex(modelType, i)
+		if ok && strings.EqualFold(column, "id") {
+			return []int{i}
+		}
+	}
+	if i, _, column := GetFieldByJson(modelType, "id"); i >= 0 && len(column) > 0 {
+		return []int{i}
+	}
+	return indexes
+}
+func isPrimaryKey(field reflect.StructField) bool {
+	for _, tag := range strings.Split(field.Tag.Get("gorm"), ";") {
+		if strings.TrimSpace(tag) == "primary_key" {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildKeysetSort returns the order by clause of keys. The columns are qualified by the table in options, if any.
+func BuildKeysetSort(keys []SortKey, d Dialect, options ...string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	sorts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		sortType := asc
+		if key.Desc {
+			sortType = desc
+		}
+		sorts = append(sorts, qualify(d, key.Column, options...)+" "+sortType)
+	}
+	return ` order by ` + strings.Join(sorts, ",")
+}
+
+// BuildKeysetCondition returns the condition of the rows after the row of which the sort values are values, with the parameters from i+1:
+// (a > ?) or (a = ? and b < ?) for "a,-b", so that the columns can be sorted in different directions.
+func BuildKeysetCondition(keys []SortKey, values []interface{}, d Dialect, buildParam func(int) string, i int, options ...string) (string, []interface{}) {
+	ors := make([]string, 0, len(keys))
+	params := make([]interface{}, 0)
+	for k, key := range keys {
+		ands := make([]string, 0, k+1)
+		for j := 0; j < k; j++ {
+			i++
+			ands = append(ands, qualify(d, keys[j].Column, options...)+" = "+buildParam(i))
+			params = append(params, values[j])
+		}
+		op := " > "
+		if key.Desc {
+			op = " < "
+		}
+		i++
+		ands = append(ands, qualify(d, key.Column, options...)+op+buildParam(i))
+		params = append(params, values[k])
+		ors = append(ors, "("+strings.Join(ands, " and ")+")")
+	}
+	return "(" + strings.Join(ors, " or ") + ")", params
+}
+
+// BuildKeysetQuery adds the keyset condition of values and the order by clause of keys to query, which is built without paging.
+// The order by clause of query, if any, is replaced, and the where clause is kept.
+// It returns an error wrapping ErrKeysetQuery if query is not a select statement of which the where and the order by clauses can be replaced,
+// such as a statement with group by, having, union, limit or a locking clause, or with a parameter in the order by clause.
+func BuildKeysetQuery(query string, params []interface{}, keys []SortKey, values []interface{}, d Dialect, buildParam func(int) string, options ...string) (string, []interface{}, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %s", ErrKeysetQuery, err.Error())
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1].text == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	main := -1
+	for i, t := range tokens {
+		if t.depth == 0 && t.kind == tokenWord && t.text == "select" {
+			main = i
+			break
+		}
+	}
+	if main < 0 || (main > 0 && tokens[0].text != "with") {
+		return "", nil, fmt.Errorf("%w: not a select statement", ErrKeysetQuery)
+	}
+	where, order, end := -1, -1, len(tokens)
+	for i := main + 1; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.depth != 0 || t.kind != tokenWord {
+			if order >= 0 && t.kind == tokenParam {
+				return "", nil, fmt.Errorf("%w: parameter in order by", ErrKeysetQuery)
+			}
+			continue
+		}
+		switch t.text {
+		case "where":
+			if where < 0 && order < 0 {
+				where = i
+			}
+		case "order":
+			if i+1 < len(tokens) && tokens[i+1].text == "by" && order < 0 {
+				order = i
+			}
+		case "top", "group", "having", "window", "union", "intersect", "except", "minus", "limit", "offset", "fetch", "for":
+			return "", nil, fmt.Errorf("%w: %s", ErrKeysetQuery, t.text)
+		}
+	}
+	if order >= 0 {
+		end = order
+	}
+	if end == 0 {
+		return "", nil, fmt.Errorf("%w: not a select statement", ErrKeysetQuery)
+	}
+	s := query[:tokens[end-1].end]
+	if len(values) > 0 {
+		condition, values2 := BuildKeysetCondition(keys, values, d, buildParam, len(params), options...)
+		if where >= 0 {
+			if where+1 >= end {
+				return "", nil, fmt.Errorf("%w: empty where clause", ErrKeysetQuery)
+			}
+			s = query[:tokens[where].end] + " (" + query[tokens[where+1].start:tokens[end-1].end] + ") and " + condition
+		} else {
+			s = s + " where " + condition
+		}
+		params = append(params, values2...)
+	}
+	return s + BuildKeysetSort(keys, d, options...), params, nil
+}
+func qualify(d Dialect, column string, options ...string) string {
+	if len(options) > 0 && len(options[0]) > 0 {
+		return QuoteName(d, options[0]+"."+column)
+	}
+	return QuoteName(d, column)
+}
+
+// EncodeCursor returns the cursor of row, which is a struct or a pointer to a struct, by the values of keys.
+func EncodeCursor(row interface{}, keys []SortKey) (Cursor, error) {
+	v := reflect.Indirect(reflect.ValueOf(row))
+	if v.Kind() != reflect.Struct {
+		return "", ErrInvalidCursor
+	}
+	values := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		values[key.Json] = v.Field(key.Index).Interface()
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return Cursor(base64.RawURLEncoding.EncodeToString(b)), nil
+}
+
+// DecodeCursor returns the sort values of cursor, which are of the types of the fields of keys in modelType,
+// or ErrInvalidCursor if cursor is not encoded by EncodeCursor with the same keys.
+func DecodeCursor(cursor Cursor, keys []SortKey, modelType reflect.Type) ([]interface{}, error) {
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	b, err := base64.RawURLEncoding.DecodeString(string(cursor))
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var raw map[string]json.RawMessage
+	if err = json.Unmarshal(b, &raw); err != nil || len(raw) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+	values := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		r, ok := raw[key.Json]
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		p := reflect.New(modelType.Field(key.Index).Type)
+		if err = json.Unmarshal(r, p.Interface()); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values = append(values, p.Elem().Interface())
+	}
+	return values, nil
+}
+
+// FindCursor returns the cursor field of the search model sm and its sort string, or nil if sm has no cursor or the cursor is nil.
+// The sort string is the Sort field of sm, or of a struct which sm has a pointer to, such as *search.SearchModel.
+func FindCursor(sm interface{}) (*Cursor, string) {
+	v := reflect.Indirect(reflect.ValueOf(sm))
+	if v.Kind() != reflect.Struct {
+		return nil, ""
+	}
+	var cursor *Cursor
+	sort := ""
+	numField := v.NumField()
+	for i := 0; i < numField; i++ {
+		field := v.Field(i)
+		if !field.CanInterface() {
+			continue
+		}
+		switch x := field.Interface().(type) {
+		case *Cursor:
+			if x != nil {
+				cursor = x
+			}
+		case string:
+			if v.Type().Field(i).Name == "Sort" {
+				sort = x
+			}
+		default:
+			if field.Kind() == reflect.Ptr && !field.IsNil() && field.Elem().Kind() == reflect.Struct {
+				if f := field.Elem().FieldByName("Sort"); f.IsValid() && f.Kind() == reflect.String && len(sort) == 0 {
+					sort = f.String()
+				}
+			}
+		}
+	}
+	return cursor, sort
+}
@@ -0,0 +1,116 @@
+package sql
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type keysetItem struct {
+	Id        string    `json:"id" gorm:"column:id;primary_key"`
+	Name      string    `json:"name" gorm:"column:name"`
+	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at"`
+}
+type keysetUser struct {
+	UserId string `json:"userId" gorm:"column:id"`
+	Name   string `json:"name" gorm:"column:name"`
+}
+
+func TestBuildSortKeys(t *testing.T) {
+	tests := []struct {
+		sort      string
+		modelType reflect.Type
+		want      []SortKey
+	}{
+		{"-createdAt,name", reflect.TypeOf(keysetItem{}), []SortKey{{2, "created_at", "createdAt", true}, {1, "name", "name", false}, {0, "id", "id", false}}},
+		{"id,unknown", reflect.TypeOf(&keysetItem{}), []SortKey{{0, "id", "id", false}}},
+		{"", reflect.TypeOf(keysetItem{}), []SortKey{{0, "id", "id", false}}},
+		{"name", reflect.TypeOf(keysetUser{}), []SortKey{{1, "name", "name", false}, {0, "id", "userId", false}}},
+		{"-name", reflect.TypeOf(auditedItem{}), []SortKey{{1, "name", "name", true}, {0, "id", "id", false}}},
+	}
+	for _, tt := range tests {
+		if got := BuildSortKeys(tt.sort, tt.modelType); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("BuildSortKeys(%q, %v) = %v, want %v", tt.sort, tt.modelType, got, tt.want)
+		}
+	}
+}
+
+func TestBuildKeysetCondition(t *testing.T) {
+	keys := []SortKey{{Column: "a"}, {Column: "b", Desc: true}, {Column: "id"}}
+	query, params := BuildKeysetCondition(keys, []interface{}{1, 2, 3}, PostgresDialect{}, BuildDollarParam, 2)
+	want := `(("a" > $3) or ("a" = $4 and "b" < $5) or ("a" = $6 and "b" = $7 and "id" > $8))`
+	if query != want || !reflect.DeepEqual(params, []interface{}{1, 1, 2, 1, 2, 3}) {
+		t.Errorf("BuildKeysetCondition() = %q %v, want %q", query, params, want)
+	}
+	query, _ = BuildKeysetCondition(keys[:1], []interface{}{1}, PostgresDialect{}, BuildDollarParam, 0, "u")
+	if want = `(("u"."a" > $1))`; query != want {
+		t.Errorf("BuildKeysetCondition() = %q, want %q", query, want)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	keys := BuildSortKeys("-createdAt", reflect.TypeOf(keysetItem{}))
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	cursor, err := EncodeCursor(&keysetItem{Id: "1", Name: "a", CreatedAt: now}, keys)
+	if err != nil {
+		t.Fatalf("EncodeCursor() error = %v", err)
+	}
+	values, err := DecodeCursor(cursor, keys, reflect.TypeOf(keysetItem{}))
+	if err != nil || !reflect.DeepEqual(values, []interface{}{now, "1"}) {
+		t.Errorf("DecodeCursor() = %v, %v, want %v", values, err, []interface{}{now, "1"})
+	}
+	if _, err = EncodeCursor("x", keys); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("EncodeCursor() of a string = %v, want ErrInvalidCursor", err)
+	}
+	for _, c := range []Cursor{"!", "e30", cursor + "x"} {
+		if _, err = DecodeCursor(c, keys, reflect.TypeOf(keysetItem{})); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) = %v, want ErrInvalidCursor", c, err)
+		}
+	}
+	other := BuildSortKeys("name", reflect.TypeOf(keysetItem{}))
+	if _, err = DecodeCursor(cursor, other, reflect.TypeOf(keysetItem{})); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("DecodeCursor() of other keys = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestBuildKeysetQuery(t *testing.T) {
+	keys := []SortKey{{Column: "name"}, {Column: "id"}}
+	values := []interface{}{"a", "1"}
+	tests := []struct {
+		query  string
+		params []interface{}
+		values []interface{}
+		want   string
+	}{
+		{"select * from users", nil, values,
+			`select * from users where (("name" > $1) or ("name" = $2 and "id" > $3)) order by "name" asc,"id" asc`},
+		{"select * from users", nil, nil,
+			`select * from users order by "name" asc,"id" asc`},
+		{"SELECT * FROM users WHERE status = $1 or age > $2 ORDER BY age desc", []interface{}{"A", 1}, values,
+			`SELECT * FROM users WHERE (status = $1 or age > $2) and (("name" > $3) or ("name" = $4 and "id" > $5)) order by "name" asc,"id" asc`},
+		{"select * from users where id in (select user_id from roles where role = $1 order by user_id)", []interface{}{"admin"}, values[:0],
+			`select * from users where id in (select user_id from roles where role = $1 order by user_id) order by "name" asc,"id" asc`},
+		{"with t as (select * from users where age > 1) select * from t where 'order by' <> name;", nil, values,
+			`with t as (select * from users where age > 1) select * from t where ('order by' <> name) and (("name" > $1) or ("name" = $2 and "id" > $3)) order by "name" asc,"id" asc`},
+	}
+	for _, tt := range tests {
+		got, params, err := BuildKeysetQuery(tt.query, tt.params, keys, tt.values, PostgresDialect{}, BuildDollarParam)
+		if err != nil || got != tt.want || len(params) != len(tt.params)+len(tt.values)*(len(tt.values)+1)/2 {
+			t.Errorf("BuildKeysetQuery(%q) = %q %v, %v, want %q", tt.query, got, params, err, tt.want)
+		}
+	}
+	for _, query := range []string{
+		"select status, count(*) from users group by status",
+		"select * from users limit 10",
+		"select * from a union select * from b",
+		"select * from users for update",
+		"select * from users order by position(name in $1)",
+		"update users set name = $1",
+		"select * from users where name = 'a",
+	} {
+		if _, _, err := BuildKeysetQuery(query, nil, keys, values, PostgresDialect{}, BuildDollarParam); !errors.Is(err, ErrKeysetQuery) {
+			t.Errorf("BuildKeysetQuery(%q) = %v, want ErrKeysetQuery", query, err)
+		}
+	}
+}
@@ -19,6 +19,9 @@ type Loader struct {
 	mapJsonColumnKeys map[string]string
 	fieldsIndex       map[string]int
 	table             string
+	softDelete        *SoftDelete
+	unscoped          bool
+	tenant            *Tenant
 }
 func NewLoader(db *sql.DB, tableName string, modelType reflect.Type, options ...func(context.Context, interface{}) (interface{}, error)) *Loader {
 	var mp func(ctx context.Context, model interface{}) (interface{}, error)
@@ -42,17 +45,52 @@ func NewSqlLoader(db *sql.DB, tableName string, modelType reflect.Type, mp func(
 	if er0 != nil {
 		panic(er0)
 	}
-	return &Loader{Database: db, BuildParam: buildParam, Map: mp, modelType: modelType, modelsType: modelsType, keys: idNames, mapJsonColumnKeys: mapJsonColumnKeys, fieldsIndex: fieldsIndex, table: tableName}
+	return &Loader{Database: db, BuildParam: buildParam, Map: mp, modelType: modelType, modelsType: modelsType, keys: idNames, mapJsonColumnKeys: mapJsonColumnKeys, fieldsIndex: fieldsIndex, table: tableName, softDelete: FindSoftDelete(modelType), tenant: FindTenant(modelType)}
 }
 
 func (s *Loader) Keys() []string {
 	return s.keys
 }
 
+// Unscoped returns a copy of the loader, which loads the soft deleted rows too.
+func (s *Loader) Unscoped() *Loader {
+	l := *s
+	l.unscoped = true
+	return &l
+}
+
+// scope appends the condition of the rows which are not soft deleted, and the condition of the tenant of ctx, to where.
+func (s *Loader) scope(ctx context.Context, where string, values []interface{}) (string, []interface{}, error) {
+	conditions := make([]string, 0)
+	dialect := GetDialect(s.Database)
+	if s.softDelete != nil && !s.unscoped {
+		conditions = append(conditions, s.softDelete.Condition(dialect))
+	}
+	if s.tenant != nil {
+		tenant, err := s.tenant.Value(ctx)
+		if err != nil {
+			return where, values, err
+		}
+		conditions = append(conditions, s.tenant.Condition(dialect, s.BuildParam(len(values)+1)))
+		values = append(values, tenant)
+	}
+	if len(conditions) == 0 {
+		return where, values, nil
+	}
+	if len(where) == 0 {
+		return " where " + strings.Join(conditions, " and "), values, nil
+	}
+	return where + " and " + strings.Join(conditions, " and "), values, nil
+}
+
 func (s *Loader) All(ctx context.Context) (interface{}, error) {
-	query := BuildSelectAllQuery(s.table)
+	where, values, err := s.scope(ctx, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	query := BuildSelectAllQuery(s.table, GetDialect(s.Database)) + where
 	result := reflect.New(s.modelsType).Interface()
-	err := Query(ctx, s.Database, result, query)
+	err = Query(ctx, s.Database, result, query, values...)
 	if err == nil {
 		if s.Map != nil {
 			return MapModels(ctx, result, s.Map)
@@ -64,6 +102,10 @@ func (s *Loader) All(ctx context.Context) (interface{}, error) {
 
 func (s *Loader) Load(ctx context.Context, ids interface{}) (interface{}, error) {
 	queryFindById, values := BuildFindById(s.Database, s.table, ids, s.mapJsonColumnKeys, s.keys, s.BuildParam)
+	queryFindById, values, err := s.scope(ctx, queryFindById, values)
+	if err != nil {
+		return nil, err
+	}
 	r, err := QueryRow(ctx, s.Database, s.modelType, s.fieldsIndex, queryFindById, values...)
 	if s.Map != nil {
 		_, er2 := s.Map(ctx, &r)
@@ -80,8 +122,9 @@ func (s *Loader) Exist(ctx context.Context, id interface{}) (bool, error) {
 	var where string
 	var values []interface{}
 	colNumber := 1
+	dialect := GetDialect(s.Database)
 	if len(s.keys) == 1 {
-		where = fmt.Sprintf("where %s = %s", s.mapJsonColumnKeys[s.keys[0]], s.BuildParam(colNumber))
+		where = fmt.Sprintf("where %s = %s", QuoteName(dialect, s.mapJsonColumnKeys[s.keys[0]]), s.BuildParam(colNumber))
 		values = append(values, id)
 		colNumber++
 	} else {
@@ -89,13 +132,17 @@ func (s *Loader) Exist(ctx context.Context, id interface{}) (bool, error) {
 		var ids = id.(map[string]interface{})
 		for k, idk := range ids {
 			columnName := s.mapJsonColumnKeys[k]
-			conditions = append(conditions, fmt.Sprintf("%s = %s", columnName, s.BuildParam(colNumber)))
+			conditions = append(conditions, fmt.Sprintf("%s = %s", QuoteName(dialect, columnName), s.BuildParam(colNumber)))
 			values = append(values, idk)
 			colNumber++
 		}
 		where = "where " + strings.Join(conditions, " and ")
 	}
-	row := s.Database.QueryRowContext(ctx, fmt.Sprintf("select count(*) from %s %s", s.table, where), values...)
+	where, values, err := s.scope(ctx, where, values)
+	if err != nil {
+		return false, err
+	}
+	row := GetExecutor(ctx, s.Database).QueryRowContext(ctx, fmt.Sprintf("select count(*) from %s %s", QuoteName(dialect, s.table), where), values...)
 	if err := row.Scan(&count); err != nil {
 		return false, err
 	} else {
@@ -109,6 +156,10 @@ func (s *Loader) Exist(ctx context.Context, id interface{}) (bool, error) {
 func (s *Loader) LoadAndDecode(ctx context.Context, id interface{}, result interface{}) (bool, error) {
 	var values []interface{}
 	sql, values := BuildFindById(s.Database, s.table, id, s.mapJsonColumnKeys, s.keys, s.BuildParam)
+	sql, values, err0 := s.scope(ctx, sql, values)
+	if err0 != nil {
+		return false, err0
+	}
 	rowData, err1 := QueryRow(ctx, s.Database, s.modelType, s.fieldsIndex, sql, values...)
 	if err1 != nil || rowData == nil {
 		return false, err1
@@ -5,35 +5,42 @@ import (
 	"database/sql"
 	"fmt"
 	"reflect"
-	"strconv"
 	"strings"
 )
 
 // raw query
 func Save(ctx context.Context, db *sql.DB, table string, model interface{}) (int64, error) {
+	if err := SetTenant(ctx, model); err != nil {
+		return 0, err
+	}
 	queryString, value, err := BuildSave(db, table, model)
 	if err != nil {
 		return 0, err
 	}
-	res, err := db.ExecContext(ctx, queryString, value...)
+	res, err := GetExecutor(ctx, db).ExecContext(ctx, queryString, value...)
 	if err != nil {
-		return 0, err
+		return 0, HandleError(db, err)
 	}
 	return res.RowsAffected()
 }
 
 func SaveTx(ctx context.Context, db *sql.DB, tx *sql.Tx, table string, model interface{}) (int64, error) {
+	if err := SetTenant(ctx, model); err != nil {
+		return -1, err
+	}
 	query, values, err0 := BuildSave(db, table, model)
 	if err0 != nil {
 		return -1, err0
 	}
 	r, err1 := tx.ExecContext(ctx, query, values...)
 	if err1 != nil {
-		return -1, err1
+		return -1, HandleError(db, err1)
 	}
 	return r.RowsAffected()
 }
 
+// BuildSave returns the upsert of model. If model has a tenant column, the tenant of a row is never updated,
+// and the row of the same key of another tenant is not changed: the update of the conflict is scoped by the tenant.
 func BuildSave(db *sql.DB, table string, model interface{}) (string, []interface{}, error) {
 	placeholders := make([]string, 0)
 	exclude := make([]string, 0)
@@ -63,62 +70,83 @@ func BuildSave(db *sql.DB, table string, model interface{}) (string, []interface
 
 	// Also append variables to mainScope
 	var setColumns []string
-	driver := GetDriver(db)
+	// the created columns are inserted, but never updated
+	created := createdColumns(modelType)
+	tenant := FindTenant(modelType)
+	dialect := GetDialect(db)
 	i := 0
-	switch driver {
-	case DriverPostgres:
+	upsert := dialect.Upsert()
+	if tenant != nil && upsert == UpsertInsertOrReplace {
+		// insert or replace deletes the row of another tenant
+		upsert = UpsertOnConflict
+	}
+	switch upsert {
+	case UpsertOnConflict, UpsertStatement:
 		uniqueCols := make([]string, 0)
 		values := make([]interface{}, 0, len(attrs)*2)
 		for ; i < len(sorted); i++ {
-			setColumns = append(setColumns, `"`+strings.Replace(sorted[i], `"`, `""`, -1)+`"`+" = EXCLUDED."+strings.Replace(sorted[i], `"`, `""`, -1))
-			dbColumns = append(dbColumns, `"`+strings.Replace(sorted[i], "`", "``", -1)+`"`)
-			variables = append(variables, "$"+strconv.Itoa(i+1))
+			column := QuoteName(dialect, sorted[i])
+			if !created[sorted[i]] && tenant.upsertColumn(sorted[i]) {
+				setColumns = append(setColumns, column+" = EXCLUDED."+column)
+			}
+			dbColumns = append(dbColumns, column)
+			variables = append(variables, dialect.BuildParam(i+1))
 			values = append(values, attrs[sorted[i]])
 		}
 		for key, val := range unique {
-			uniqueCols = append(uniqueCols, `"`+strings.Replace(key, `"`, `""`, -1)+`"`)
-			dbColumns = append(dbColumns, `"`+strings.Replace(key, "`", "``", -1)+`"`)
-			variables = append(variables, "$"+strconv.Itoa(i+1))
+			uniqueCols = append(uniqueCols, QuoteName(dialect, key))
+			dbColumns = append(dbColumns, QuoteName(dialect, key))
+			variables = append(variables, dialect.BuildParam(i+1))
 			values = append(values, val)
 			i++
 		}
+		if upsert == UpsertStatement && len(created) == 0 && tenant == nil {
+			query := fmt.Sprintf("UPSERT INTO %s (%s) VALUES (%s)", QuoteName(dialect, table), strings.Join(dbColumns, ", "), strings.Join(variables, ", "))
+			return query, values, nil
+		}
 		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s",
-			`"`+strings.Replace(table, `"`, `""`, -1)+`"`,
+			QuoteName(dialect, table),
 			strings.Join(dbColumns, ", "),
 			strings.Join(variables, ", "),
 			strings.Join(uniqueCols, ", "),
 			strings.Join(setColumns, ", "),
 		)
+		if tenant != nil {
+			query = query + " WHERE " + tenant.excluded(dialect, table)
+		}
 		return query, values, nil
-	case DriverOracle:
+	case UpsertMergeDual:
 		uniqueCols := make([]string, 0)
 		inColumns := make([]string, 0)
 		values := make([]interface{}, 0, len(attrs)*2)
 		insertCols := make([]string, 0)
 		for v, key := range sorted {
 			values = append(values, attrs[key])
-			tkey := `"` + strings.Replace(key, `"`, `""`, -1) + `"`
-			tkey = strings.ToUpper(tkey)
-			setColumns = append(setColumns, "a."+tkey+" = temp."+tkey)
-			inColumns = append(inColumns, "temp."+key)
+			tkey := QuoteName(dialect, key)
+			if !created[key] && tenant.upsertColumn(key) {
+				setColumns = append(setColumns, "a."+tkey+" = temp."+tkey)
+			}
+			inColumns = append(inColumns, "temp."+tkey)
 			variables = append(variables, fmt.Sprintf(":%d "+tkey, v))
 			insertCols = append(insertCols, tkey)
 		}
 		for key, val := range unique {
-			tkey := `"` + strings.Replace(key, `"`, `""`, -1) + `"`
-			tkey = strings.ToUpper(tkey)
+			tkey := QuoteName(dialect, key)
 			onDupe := "a." + tkey + " = " + "temp." + tkey
 			uniqueCols = append(uniqueCols, onDupe)
 			variables = append(variables, fmt.Sprintf(":%s "+tkey, key))
-			inColumns = append(inColumns, "temp."+key)
+			inColumns = append(inColumns, "temp."+tkey)
 			values = append(values, val)
 			insertCols = append(insertCols, tkey)
 		}
+		if tenant != nil {
+			uniqueCols = append(uniqueCols, tenant.matched(dialect, "a", "temp"))
+		}
 		//for _, key := range sorted {
 		//	value = append(value, attrs[key])
 		//}
 		query := fmt.Sprintf("MERGE INTO %s a USING (SELECT %s FROM dual) temp ON  (%s) WHEN MATCHED THEN UPDATE SET %s WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)",
-			`"`+strings.Replace(table, `"`, `""`, -1)+`"`,
+			QuoteName(dialect, table),
 			strings.Join(variables, ", "),
 			strings.Join(uniqueCols, " AND "),
 			strings.Join(setColumns, ", "),
@@ -126,7 +154,7 @@ func BuildSave(db *sql.DB, table string, model interface{}) (string, []interface
 			strings.Join(inColumns, ", "),
 		)
 		return query, values, nil
-	case DriverMysql:
+	case UpsertOnDuplicateKey:
 		values := make([]interface{}, 0, len(attrs)*2)
 		updates := make([]interface{}, 0)
 		for key, val := range unique {
@@ -134,7 +162,7 @@ func BuildSave(db *sql.DB, table string, model interface{}) (string, []interface
 			//mainScope.AddToVars(attrs[key])
 			if notNil {
 				v, ok := GetDBValue(val)
-				dbColumns = append(dbColumns, "`"+strings.Replace(key, "`", "``", -1)+"`")
+				dbColumns = append(dbColumns, QuoteName(dialect, key))
 				if ok {
 					variables = append(variables, v)
 				} else {
@@ -148,24 +176,30 @@ func BuildSave(db *sql.DB, table string, model interface{}) (string, []interface
 			val, notNil := nAttrs[key]
 			if notNil {
 				v, ok := GetDBValue(val)
-				dbColumns = append(dbColumns, "`"+strings.Replace(key, "`", "``", -1)+"`")
+				dbColumns = append(dbColumns, QuoteName(dialect, key))
 				if ok {
-					setColumns = append(setColumns, "`"+strings.Replace(key, "`", "``", -1)+"`"+" = "+v)
 					variables = append(variables, v)
 				} else {
-					setColumns = append(setColumns, "`"+strings.Replace(key, "`", "``", -1)+"`"+" = ?")
 					variables = append(variables, "?")
 					values = append(values, val)
+				}
+				if created[key] || !tenant.upsertColumn(key) {
+					continue
+				}
+				if ok {
+					setColumns = append(setColumns, QuoteName(dialect, key)+" = "+tenant.ifSameTenant(dialect, key, v))
+				} else {
+					setColumns = append(setColumns, QuoteName(dialect, key)+" = "+tenant.ifSameTenant(dialect, key, "?"))
 					updates = append(updates, val)
 				}
-			} else {
-				setColumns = append(setColumns, "`"+strings.Replace(key, "`", "``", -1)+"`"+" = null")
+			} else if !created[key] && tenant.upsertColumn(key) {
+				setColumns = append(setColumns, QuoteName(dialect, key)+" = "+tenant.ifSameTenant(dialect, key, "null"))
 			}
 		}
 		valueQuery := "(" + strings.Join(variables, ", ") + ")"
 		placeholders = append(placeholders, valueQuery)
 		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s ON DUPLICATE KEY UPDATE %s",
-			"`"+strings.Replace(table, "`", "``", -1)+"`",
+			QuoteName(dialect, table),
 			strings.Join(dbColumns, ", "),
 			strings.Join(placeholders, ", "),
 			strings.Join(setColumns, ", "),
@@ -174,27 +208,32 @@ func BuildSave(db *sql.DB, table string, model interface{}) (string, []interface
 			values = append(values, s)
 		}
 		return query, values, nil
-	case DriverMssql:
+	case UpsertMerge:
 		uniqueCols := make([]string, 0)
 		values := make([]interface{}, 0, len(attrs)*2)
 		for _, key := range sorted {
 			//mainScope.AddToVars(attrs[key])
-			tkey := `"` + strings.Replace(key, `"`, `""`, -1) + `"`
+			tkey := QuoteName(dialect, key)
 			dbColumns = append(dbColumns, tkey)
 			variables = append(variables, "?")
 			values = append(values, attrs[key])
-			setColumns = append(setColumns, tkey+" = temp."+tkey)
+			if !created[key] && tenant.upsertColumn(key) {
+				setColumns = append(setColumns, tkey+" = temp."+tkey)
+			}
 		}
 		for i, val := range unique {
-			tkey := `"` + strings.Replace(i, `"`, `""`, -1) + `"`
-			dbColumns = append(dbColumns, `"`+strings.Replace(tkey, `"`, `""`, -1)+`"`)
+			tkey := QuoteName(dialect, i)
+			dbColumns = append(dbColumns, tkey)
 			variables = append(variables, "?")
 			values = append(values, val)
-			onDupe := table + "." + tkey + " = " + "temp." + tkey
+			onDupe := QuoteName(dialect, table) + "." + tkey + " = " + "temp." + tkey
 			uniqueCols = append(uniqueCols, onDupe)
 		}
+		if tenant != nil {
+			uniqueCols = append(uniqueCols, tenant.matched(dialect, QuoteName(dialect, table), "temp"))
+		}
 		query := fmt.Sprintf("MERGE INTO %s USING (VALUES %s) AS temp (%s) ON %s WHEN MATCHED THEN UPDATE SET %s WHEN NOT MATCHED THEN INSERT (%s) VALUES %s;",
-			`"`+strings.Replace(table, `"`, `""`, -1)+`"`,
+			QuoteName(dialect, table),
 			strings.Join(variables, ", "),
 			strings.Join(dbColumns, ", "),
 			strings.Join(uniqueCols, " AND "),
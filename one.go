@@ -29,14 +29,16 @@ func Find(slice []string, val string) (int, bool) {
 	return -1, false
 }
 
-func BuildInsert(table string, model interface{}, i int, buildParam func(int) string) (string, []interface{}) {
+func BuildInsert(table string, model interface{}, i int, buildParam func(int) string, options ...Dialect) (string, []interface{}) {
+	d := getDialect(options)
 	mapData, mapKey, columns, keys := BuildMapDataAndKeys(model, false)
+	skipAutoIncrement(model, mapData, mapKey)
 	var cols []string
 	var values []interface{}
 	var params []string
 	for _, columnName := range keys {
 		if value, ok := mapKey[columnName]; ok {
-			cols = append(cols, QuoteColumnName(columnName))
+			cols = append(cols, QuoteName(d, columnName))
 			v2b, ok2 := GetDBValue(value)
 			if ok2 {
 				params = append(params, v2b)
@@ -50,7 +52,7 @@ func BuildInsert(table string, model interface{}, i int, buildParam func(int) st
 	}
 	for _, columnName := range columns {
 		if v1, ok := mapData[columnName]; ok {
-			cols = append(cols, QuoteColumnName(columnName))
+			cols = append(cols, QuoteName(d, columnName))
 			v1b, ok1 := GetDBValue(v1)
 			if ok1 {
 				params = append(params, v1b)
@@ -63,64 +65,64 @@ func BuildInsert(table string, model interface{}, i int, buildParam func(int) st
 		}
 	}
 	column := strings.Join(cols, ",")
-	return fmt.Sprintf("insert into %v(%v)values(%v)", table, column, strings.Join(params, ",")), values
+	return fmt.Sprintf("insert into %v(%v)values(%v)", QuoteName(d, table), column, strings.Join(params, ",")), values
 }
 
-func BuildInsertWithVersion(table string, model interface{}, i int, versionIndex int, buildParam func(int) string) (string, []interface{}) {
+// BuildInsertWithVersion sets the first version of model, and panics if it cannot be set; InsertWithVersion returns the error instead.
+func BuildInsertWithVersion(table string, model interface{}, i int, versionIndex int, buildParam func(int) string, options ...Dialect) (string, []interface{}) {
 	if versionIndex < 0 {
 		panic("version index not found")
 	}
-
-	var versionValue int64 = 1
-	_, err := setValue(model, versionIndex, &versionValue)
+	query, values, err := buildInsertWithVersion(table, model, i, versionIndex, getVersionStrategy(model, versionIndex), buildParam, getDialect(options))
 	if err != nil {
 		panic(err)
 	}
-	mapData, mapKey, columns, keys := BuildMapDataAndKeys(model, false)
-	var cols []string
-	var values []interface{}
-	var params []string
-	for _, columnName := range keys {
-		if value, ok := mapKey[columnName]; ok {
-			cols = append(cols, QuoteColumnName(columnName))
-			v2b, ok2 := GetDBValue(value)
-			if ok2 {
-				params = append(params, v2b)
-			} else {
-				values = append(values, value)
-				p := buildParam(i)
-				params = append(params, p)
-				i++
-			}
-		}
+	return query, values
+}
+func buildInsertWithVersion(table string, model interface{}, i int, versionIndex int, strategy VersionStrategy, buildParam func(int) string, d Dialect) (string, []interface{}, error) {
+	version, err := strategy.Next(nil)
+	if err != nil {
+		return "", nil, err
 	}
-	for _, columnName := range columns {
-		if v1, ok := mapData[columnName]; ok {
-			cols = append(cols, QuoteColumnName(columnName))
-			v1b, ok1 := GetDBValue(v1)
-			if ok1 {
-				params = append(params, v1b)
-			} else {
-				values = append(values, v1)
-				p := buildParam(i)
-				params = append(params, p)
-				i++
+	if err = setVersion(model, versionIndex, version); err != nil {
+		return "", nil, err
+	}
+	query, values := BuildInsert(table, model, i, buildParam, d)
+	return query, values, nil
+}
+
+// FindAutoIncrement returns the index and the column of the field tagged by autoIncrement, or -1 if there is no such field.
+func FindAutoIncrement(modelType reflect.Type) (int, string) {
+	numField := modelType.NumField()
+	for i := 0; i < numField; i++ {
+		tag, _ := modelType.Field(i).Tag.Lookup("gorm")
+		for _, s := range strings.Split(tag, ";") {
+			s = strings.ToLower(strings.TrimSpace(s))
+			if s == "autoincrement" || s == "auto_increment" || s == "autoincrement:true" {
+				if col, ok := GetColumnNameByIndex(modelType, i); ok {
+					return i, col
+				}
 			}
 		}
 	}
-	column := strings.Join(cols, ",")
-	return fmt.Sprintf("insert into %v(%v)values(%v)", table, column, strings.Join(params, ",")), values
+	return -1, ""
+}
+
+// skipAutoIncrement removes the auto-increment column if its value is zero, so that the database generates it.
+func skipAutoIncrement(model interface{}, mapData map[string]interface{}, mapKey map[string]interface{}) {
+	mv := reflect.Indirect(reflect.ValueOf(model))
+	if mv.Kind() != reflect.Struct {
+		return
+	}
+	if index, col := FindAutoIncrement(mv.Type()); index >= 0 && mv.Field(index).IsZero() {
+		delete(mapData, col)
+		delete(mapKey, col)
+	}
 }
 
-func QuoteColumnName(str string) string {
-	//if strings.Contains(str, ".") {
-	//	var newStrs []string
-	//	for _, str := range strings.Split(str, ".") {
-	//		newStrs = append(newStrs, str)
-	//	}
-	//	return strings.Join(newStrs, ".")
-	//}
-	return str
+// QuoteColumnName quotes str with the dialect in options; without dialect, it only validates str.
+func QuoteColumnName(str string, options ...Dialect) string {
+	return QuoteName(getDialect(options), str)
 }
 
 func BuildMapDataAndKeys(model interface{}, update bool) (map[string]interface{}, map[string]interface{}, []string, []string) {
@@ -170,7 +172,7 @@ func CheckByIndex(modelType reflect.Type, index int, update bool) (col string, i
 		return "", false, false
 	}
 	if update {
-		if strings.Contains(tag, "update:false") {
+		if strings.Contains(tag, "update:false") || isCreatedField(tag) {
 			return "", false, false
 		}
 	}
@@ -191,14 +193,11 @@ func CheckByIndex(modelType reflect.Type, index int, update bool) (col string, i
 }
 
 func QuoteByDriver(key, driver string) string {
-	switch driver {
-	case DriverMysql:
-		return fmt.Sprintf("`%s`", key)
-	case DriverMssql:
-		return fmt.Sprintf(`[%s]`, key)
-	default:
-		return fmt.Sprintf(`"%s"`, key)
+	d, ok := GetDialectByName(driver)
+	if !ok {
+		d = DefaultDialect{}
 	}
+	return QuoteName(d, key)
 }
 
 func BuildResult(result int64, err error) (int64, error) {
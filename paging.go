@@ -0,0 +1,107 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+)
+
+// The paging strategies of BuildFromQueryWithPaging, which count the rows of a search.
+const (
+	// PagingCount loads the page, and counts the rows by a count query.
+	PagingCount = "count"
+	// PagingWindow loads the page with count(*) over(), in one query, if the dialect is a WindowCounter; otherwise, it is PagingCount.
+	PagingWindow = "window"
+	// PagingEstimate loads the page, and estimates the number of the rows if the dialect is a CountEstimator, such as postgres; otherwise, it is PagingCount.
+	PagingEstimate = "estimate"
+	// PagingNoCount loads one more row than the page size, and does not count the rows.
+	// The total is the number of the rows up to the end of the page, plus one if there is a next page.
+	PagingNoCount = "no_count"
+)
+
+// BuildFromQueryWithPaging is BuildFromQuery, which counts the rows by paging, such as PagingWindow.
+// The default paging is PagingWindow for oracle, and PagingCount for the other databases.
+func BuildFromQueryWithPaging(ctx context.Context, db *sql.DB, models interface{}, query string, params []interface{}, pageIndex int64, pageSize int64, initPageSize int64, paging string, options ...func(context.Context, interface{}) (interface{}, error)) (int64, error) {
+	var mp func(context.Context, interface{}) (interface{}, error)
+	if len(options) > 0 && options[0] != nil {
+		mp = options[0]
+	}
+	rows := reflect.Indirect(reflect.ValueOf(models))
+	if pageSize <= 0 {
+		if er1 := Query(ctx, db, models, query, params...); er1 != nil {
+			return -1, er1
+		}
+		var total int64
+		if rows.Kind() == reflect.Slice {
+			total = int64(rows.Len())
+		}
+		er2 := BuildSearchResult(ctx, models, mp)
+		return total, er2
+	}
+	d := GetDialect(db)
+	if len(paging) == 0 {
+		paging = PagingCount
+		if d.Name() == DriverOracle {
+			paging = PagingWindow
+		}
+	}
+	limit, offset := getLimitOffset(pageIndex, pageSize, initPageSize)
+	switch paging {
+	case PagingWindow:
+		if w, ok := d.(WindowCounter); ok && w.WindowCount() {
+			if queryWindow, ok := buildWindowCount(query); ok {
+				var total int64
+				if er1 := QueryAndCount(ctx, db, models, &total, queryWindow+d.BuildPaging(limit, offset), params...); er1 != nil {
+					return -1, er1
+				}
+				if rows.Len() == 0 && offset > 0 {
+					// there is no row to have the count of a page after the last page
+					total = count(ctx, db, query, params)
+				}
+				er2 := BuildSearchResult(ctx, models, mp)
+				return total, er2
+			}
+		}
+	case PagingEstimate:
+		if e, ok := d.(CountEstimator); ok {
+			if er1 := Query(ctx, db, models, query+d.BuildPaging(limit, offset), params...); er1 != nil {
+				return -1, er1
+			}
+			total, er2 := e.EstimateCount(ctx, GetExecutor(ctx, db), query, params)
+			if er2 != nil {
+				total = count(ctx, db, query, params)
+			} else if loaded := offset + int64(rows.Len()); total < loaded {
+				total = loaded
+			}
+			er3 := BuildSearchResult(ctx, models, mp)
+			return total, er3
+		}
+	case PagingNoCount:
+		if er1 := Query(ctx, db, models, query+d.BuildPaging(limit+1, offset), params...); er1 != nil {
+			return -1, er1
+		}
+		total := offset + int64(rows.Len())
+		if int64(rows.Len()) > limit {
+			rows.Set(rows.Slice(0, int(limit)))
+			total = offset + limit + 1
+		}
+		er2 := BuildSearchResult(ctx, models, mp)
+		return total, er2
+	}
+	if er1 := Query(ctx, db, models, query+d.BuildPaging(limit, offset), params...); er1 != nil {
+		return -1, er1
+	}
+	total := count(ctx, db, query, params)
+	er2 := BuildSearchResult(ctx, models, mp)
+	return total, er2
+}
+
+// count returns the number of the rows of query, or 0 if they cannot be counted.
+func count(ctx context.Context, db *sql.DB, query string, params []interface{}) int64 {
+	queryCount, paramsCount := BuildCountQuery(query, params)
+	total, err := Count(ctx, db, queryCount, paramsCount...)
+	if err != nil {
+		return 0
+	}
+	return total
+}
@@ -56,11 +56,20 @@ func (s *PasscodeService) Save(ctx context.Context, id string, passcode string,
 	var placeholder []string
 	columns := []string{s.idName, s.passcodeName, s.expiredAtName}
 	var queryString string
-	driver := GetDriver(s.db)
+	args := []interface{}{id, passcode, expireAt, id, passcode, expireAt}
+	dialect := GetDialect(s.db)
+	upsert := dialect.Upsert()
 	for i := 0; i < 3; i++ {
 		placeholder = append(placeholder, s.BuildParam(i+1))
 	}
-	if driver == DriverPostgres {
+	if upsert == UpsertStatement {
+		queryString = fmt.Sprintf("UPSERT INTO %s (%s) VALUES %s",
+			s.tableName,
+			strings.Join(columns, ", "),
+			"("+strings.Join(placeholder, ", ")+")",
+		)
+		args = args[:3]
+	} else if upsert == UpsertOnConflict {
 		setColumns := make([]string, 0)
 		for i, key := range columns {
 			setColumns = append(setColumns, key+" = "+s.BuildParam(i+4))
@@ -72,7 +81,7 @@ func (s *PasscodeService) Save(ctx context.Context, id string, passcode string,
 			s.idName,
 			strings.Join(setColumns, ", "),
 		)
-	} else if driver == DriverMysql {
+	} else if upsert == UpsertOnDuplicateKey {
 		setColumns := make([]string, 0)
 		for i, key := range columns {
 			setColumns = append(setColumns, key+" = "+s.BuildParam(i+3))
@@ -84,7 +93,7 @@ func (s *PasscodeService) Save(ctx context.Context, id string, passcode string,
 			"("+strings.Join(placeholder, ", ")+")",
 			strings.Join(setColumns, ", "),
 		)
-	} else if driver == DriverOracle {
+	} else if upsert == UpsertMergeDual {
 		var placeholderOracle []string
 		for i := 0; i < 3; i++ {
 			placeholderOracle = append(placeholderOracle, s.BuildParam(i+4))
@@ -107,7 +116,7 @@ func (s *PasscodeService) Save(ctx context.Context, id string, passcode string,
 			strings.Join(columns, ", "),
 			strings.Join(placeholderOracle, ", "),
 		)
-	} else if driver == DriverMssql {
+	} else if upsert == UpsertMerge {
 		setColumns := make([]string, 0)
 		onDupe := s.tableName + "." + s.idName + " = " + "temp." + s.idName
 		for _, key := range columns {
@@ -122,7 +131,7 @@ func (s *PasscodeService) Save(ctx context.Context, id string, passcode string,
 			strings.Join(columns, ", "),
 			strings.Join(placeholder, ", "),
 		)
-	} else if driver == DriverSqlite3 {
+	} else if upsert == UpsertInsertOrReplace {
 		setColumns := make([]string, 0)
 		for i, key := range columns {
 			setColumns = append(setColumns, key+" = "+s.BuildParam(i+3))
@@ -133,14 +142,14 @@ func (s *PasscodeService) Save(ctx context.Context, id string, passcode string,
 			"("+strings.Join(placeholder, ", ")+")",
 		)
 	} else {
-		return 0, fmt.Errorf("unsupported db vendor, current vendor is %s", driver)
+		return 0, fmt.Errorf("unsupported db vendor, current vendor is %s", dialect.Name())
 	}
 	if len(s.Tx) > 0 {
 		txv := ctx.Value(s.Tx)
 		if txv != nil {
 			tx, ok := txv.(*sql.Tx)
 			if ok {
-				x0, er0 := tx.ExecContext(ctx, queryString, id, passcode, expireAt, id, passcode, expireAt)
+				x0, er0 := tx.ExecContext(ctx, queryString, args...)
 				if er0 != nil {
 					return 0, er0
 				}
@@ -148,7 +157,7 @@ func (s *PasscodeService) Save(ctx context.Context, id string, passcode string,
 			}
 		}
 	}
-	x, err := s.db.ExecContext(ctx, queryString, id, passcode, expireAt, id, passcode, expireAt)
+	x, err := GetExecutor(ctx, s.db).ExecContext(ctx, queryString, args...)
 	if err != nil {
 		return 0, err
 	}
@@ -159,7 +168,7 @@ func (s *PasscodeService) Load(ctx context.Context, id string) (string, time.Tim
 	driverName := GetDriver(s.db)
 	arr := make(map[string]interface{})
 	strSql := fmt.Sprintf(`SELECT %s, %s FROM `, s.passcodeName, s.expiredAtName) + s.tableName + ` WHERE ` + s.idName + ` = ` + s.BuildParam(1)
-	rows, err := s.db.QueryContext(ctx, strSql, id)
+	rows, err := GetExecutor(ctx, s.db).QueryContext(ctx, strSql, id)
 	if err != nil {
 		return "", time.Now().Add(-24 * time.Hour), err
 	}
@@ -210,7 +219,7 @@ func (s *PasscodeService) Load(ctx context.Context, id string) (string, time.Tim
 
 func (s *PasscodeService) Delete(ctx context.Context, id string) (int64, error) {
 	strSQL := `DELETE FROM ` + s.tableName + ` WHERE ` + s.idName + ` =  ` + s.BuildParam(1)
-	x, err := s.db.ExecContext(ctx, strSQL, id)
+	x, err := GetExecutor(ctx, s.db).ExecContext(ctx, strSQL, id)
 	if err != nil {
 		return 0, err
 	}
@@ -3,9 +3,7 @@ package sql
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"reflect"
-	"strconv"
 	"strings"
 )
 
@@ -17,48 +15,37 @@ const (
 )
 
 func BuildFromQuery(ctx context.Context, db *sql.DB, models interface{}, query string, params []interface{}, pageIndex int64, pageSize int64, initPageSize int64, options...func(context.Context, interface{}) (interface{}, error)) (int64, error) {
+	return BuildFromQueryWithPaging(ctx, db, models, query, params, pageIndex, pageSize, initPageSize, "", options...)
+}
+
+// BuildFromCursor loads the page of limit rows of query, which is built with the keyset condition and the order by clause of keys, such as by BuildKeysetQuery,
+// and sets cursor to the cursor of the next page, or to an empty cursor if it is the last page. It returns the number of the loaded rows, because the rows are not counted.
+func BuildFromCursor(ctx context.Context, db *sql.DB, models interface{}, query string, params []interface{}, limit int64, cursor *Cursor, keys []SortKey, options ...func(context.Context, interface{}) (interface{}, error)) (int64, error) {
 	var mp func(context.Context, interface{}) (interface{}, error)
 	if len(options) > 0 && options[0] != nil {
 		mp = options[0]
 	}
-	var total int64
-	driver := GetDriver(db)
-	if pageSize <= 0 {
-		er1 := Query(ctx, db, models, query, params...)
-		if er1 != nil {
-			return -1, er1
-		}
-		objectValues := reflect.Indirect(reflect.ValueOf(models))
-		if objectValues.Kind() == reflect.Slice {
-			i := objectValues.Len()
-			total = int64(i)
-		}
-		er2 := BuildSearchResult(ctx, models, mp)
-		return total, er2
-	} else {
-		if driver == DriverOracle {
-			queryPaging := BuildPagingQueryByDriver(query, pageIndex, pageSize, initPageSize, driver)
-			er1 := QueryAndCount(ctx, db, models, &total, queryPaging, params...)
-			if er1 != nil {
-				return -1, er1
-			}
-			er2 := BuildSearchResult(ctx, models, mp)
-			return total, er2
-		} else {
-			queryPaging := BuildPagingQuery(query, pageIndex, pageSize, initPageSize, driver)
-			queryCount, paramsCount := BuildCountQuery(query, params)
-			er1 := Query(ctx, db, models, queryPaging, params...)
-			if er1 != nil {
-				return -1, er1
-			}
-			total, er2 := Count(ctx, db, queryCount, paramsCount...)
-			if er2 != nil {
-				total = 0
-			}
-			er3 := BuildSearchResult(ctx, models, mp)
-			return total, er3
+	if limit > 0 {
+		query = query + GetDialect(db).BuildPaging(limit+1, 0)
+	}
+	if er1 := Query(ctx, db, models, query, params...); er1 != nil {
+		return -1, er1
+	}
+	var next Cursor
+	rows := reflect.Indirect(reflect.ValueOf(models))
+	if limit > 0 && int64(rows.Len()) > limit {
+		rows.Set(rows.Slice(0, int(limit)))
+		c, er2 := EncodeCursor(rows.Index(int(limit)-1).Interface(), keys)
+		if er2 != nil {
+			return -1, er2
 		}
+		next = c
 	}
+	if cursor != nil {
+		*cursor = next
+	}
+	er3 := BuildSearchResult(ctx, models, mp)
+	return int64(rows.Len()), er3
 }
 func BuildPagingQueryByDriver(sql string, pageIndex int64, pageSize int64, initPageSize int64, driver string) string {
 	s2 := BuildPagingQuery(sql, pageIndex, pageSize, initPageSize, driver)
@@ -85,33 +72,32 @@ func BuildPagingQueryByDriver(sql string, pageIndex int64, pageSize int64, initP
 }
 func BuildPagingQuery(sql string, pageIndex int64, pageSize int64, initPageSize int64, driver string) string {
 	if pageSize > 0 {
-		var limit, offset int64
-		if initPageSize > 0 {
-			if pageIndex == 1 {
-				limit = initPageSize
-				offset = 0
-			} else {
-				limit = pageSize
-				offset = pageSize*(pageIndex-2) + initPageSize
-			}
-		} else {
-			limit = pageSize
-			offset = pageSize * (pageIndex - 1)
-		}
-
-		var pagingQuery string
-		if driver == DriverOracle {
-			pagingQuery = fmt.Sprintf(OraclePagingFormat, strconv.Itoa(int(offset)), strconv.Itoa(int(limit)))
-		} else {
-			pagingQuery = fmt.Sprintf(DefaultPagingFormat, strconv.Itoa(int(limit)), strconv.Itoa(int(offset)))
+		limit, offset := getLimitOffset(pageIndex, pageSize, initPageSize)
+		d, ok := GetDialectByName(driver)
+		if !ok {
+			d = DefaultDialect{}
 		}
-		sql += pagingQuery
+		sql += d.BuildPaging(limit, offset)
 	}
 
 	return sql
 }
+func getLimitOffset(pageIndex int64, pageSize int64, initPageSize int64) (int64, int64) {
+	if initPageSize > 0 {
+		if pageIndex == 1 {
+			return initPageSize, 0
+		}
+		return pageSize, pageSize*(pageIndex-2) + initPageSize
+	}
+	return pageSize, pageSize * (pageIndex - 1)
+}
 
+// BuildCountQuery returns the query which counts the rows of sql, which is parsed to count the statements with distinct, group by, union, common table expressions or limit in a subquery.
+// If sql cannot be parsed, the count query is built from the first " from " and " order by " of sql.
 func BuildCountQuery(sql string, params []interface{}) (string, []interface{}) {
+	if count, ok := buildCount(sql); ok {
+		return count, params
+	}
 	i := strings.Index(sql, "select ")
 	if i < 0 {
 		return sql, params
@@ -0,0 +1,78 @@
+package query
+
+import "strings"
+
+// condition is a condition with parameters, or a group of conditions joined by op.
+// The parameters are numbered when the condition is rendered, in the order of the placeholders in the query, so that the conditions can be grouped in any order.
+type condition struct {
+	// parts are the texts around the placeholders of the values
+	parts  []string
+	values []interface{}
+	op     string
+	items  []*condition
+	// not negates the condition of the only item
+	not bool
+}
+
+// compare returns the condition "column operator ?".
+func compare(column string, operator string, value interface{}) *condition {
+	return &condition{parts: []string{column + " " + operator + " ", ""}, values: []interface{}{value}}
+}
+
+// list returns the condition "column operator (?,?,...)".
+func list(column string, operator string, values []interface{}) *condition {
+	parts := make([]string, 0, len(values)+1)
+	parts = append(parts, column+" "+operator+" (")
+	for i := 1; i < len(values); i++ {
+		parts = append(parts, ",")
+	}
+	parts = append(parts, ")")
+	return &condition{parts: parts, values: values}
+}
+
+// group returns the conditions joined by op, or nil if there is no condition.
+func group(op string, items ...*condition) *condition {
+	if len(items) == 0 {
+		return nil
+	}
+	return &condition{op: op, items: items}
+}
+
+// add appends item, of which the conditions are appended if they are joined by the same op.
+func (c *condition) add(item *condition) {
+	if item.op == c.op && item.items != nil {
+		c.items = append(c.items, item.items...)
+	} else {
+		c.items = append(c.items, item)
+	}
+}
+
+// render returns the sql of c, and appends its parameters to values.
+func (c *condition) render(buildParam func(int) string, values *[]interface{}) string {
+	if c.not {
+		item := c.items[0]
+		if len(item.items) > 1 {
+			// a group is rendered in parentheses
+			return "not " + item.render(buildParam, values)
+		}
+		return "not (" + item.render(buildParam, values) + ")"
+	}
+	if c.items == nil {
+		var sb strings.Builder
+		sb.WriteString(c.parts[0])
+		for i, v := range c.values {
+			*values = append(*values, v)
+			sb.WriteString(buildParam(len(*values)))
+			sb.WriteString(c.parts[i+1])
+		}
+		return sb.String()
+	}
+	if len(c.items) == 1 {
+		return c.items[0].render(buildParam, values)
+	}
+	sqls := make([]string, 0, len(c.items))
+	for _, item := range c.items {
+		sqls = append(sqls, item.render(buildParam, values))
+	}
+	return "(" + strings.Join(sqls, c.op) + ")"
+}
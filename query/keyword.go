@@ -0,0 +1,148 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+
+	d "github.com/core-go/sql"
+)
+
+// keywordColumn is a column of the keyword search, which is tagged by `keyword:"prefix"`, `keyword:"contain"`, `keyword:"equal"` or `keyword:"fulltext"`.
+type keywordColumn struct {
+	column   string
+	format   string
+	fulltext bool
+}
+
+// getKeywordColumns returns the keyword columns of the fields of t, of which the column names are the gorm columns of t, or of the model.
+func (b *filterBuilder) getKeywordColumns(t reflect.Type) []keywordColumn {
+	columns := make([]keywordColumn, 0)
+	numField := t.NumField()
+	for i := 0; i < numField; i++ {
+		field := t.Field(i)
+		if c, ok := b.keywordColumn(field, t); ok {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+func (b *filterBuilder) keywordColumn(field reflect.StructField, t reflect.Type) (keywordColumn, bool) {
+	key, ok := field.Tag.Lookup("keyword")
+	if !ok {
+		return keywordColumn{}, false
+	}
+	if len(key) == 0 {
+		key = "contain"
+	}
+	format, exist := keywordFormat[key]
+	if !exist && key != fulltext {
+		b.fail(invalidFilter("keyword format %q of field %s is not supported", key, field.Name))
+		return keywordColumn{}, false
+	}
+	columnName, existCol := getColumnName(t, field.Name)
+	if !existCol {
+		columnName, _ = getColumnName(b.modelType, field.Name)
+	}
+	if c := getColumnNameFromSqlBuilderTag(field); c != nil {
+		columnName = *c
+	} else if len(columnName) > 0 {
+		columnName = d.QuoteName(b.dialect, columnName)
+	}
+	if len(columnName) == 0 {
+		return keywordColumn{}, false
+	}
+	return keywordColumn{column: columnName, format: format, fulltext: key == fulltext}, true
+}
+
+// keywordCondition returns the condition of the rows of which a keyword column matches the keyword,
+// or, if words is true, of which each word of the keyword is matched by a keyword column.
+func (b *filterBuilder) keywordCondition(keyword string, words bool) *condition {
+	if len(b.keywordColumns) == 0 {
+		b.keywordColumns = b.getKeywordColumns(b.modelType)
+	}
+	if len(b.keywordColumns) == 0 {
+		return nil
+	}
+	values := []string{keyword}
+	if words {
+		values = strings.Fields(keyword)
+	}
+	conditions := make([]*condition, 0, len(values))
+	for _, value := range values {
+		matches := make([]*condition, 0, len(b.keywordColumns))
+		for _, c := range b.keywordColumns {
+			if c.fulltext {
+				if m := b.fulltext(c.column, value); m != nil {
+					matches = append(matches, m)
+				}
+			} else {
+				matches = append(matches, b.like(c.column, strings.Replace(c.format, "?", b.escapeLike(value), -1)))
+			}
+		}
+		if g := group(" or ", matches...); g != nil {
+			conditions = append(conditions, g)
+		}
+	}
+	return group(" and ", conditions...)
+}
+
+// like returns the condition "column like ?", which is case-insensitive for postgres, of pattern, in which \ escapes %, _ and itself.
+func (b *filterBuilder) like(column string, pattern string) *condition {
+	operator := like
+	if b.driver == d.DriverPostgres {
+		operator = `ilike`
+	}
+	c := compare(column, operator, pattern)
+	switch b.dialect.Name() {
+	case d.DriverPostgres, d.DriverMysql:
+		// \ is the default escape character
+	default:
+		c.parts[1] = ` escape '\'`
+	}
+	return c
+}
+
+// escapeLike escapes the wildcards of s, so that s is matched as it is in a like pattern.
+// For mssql, [ is escaped too, because it starts a character class.
+func (b *filterBuilder) escapeLike(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `%`, `\%`, -1)
+	s = strings.Replace(s, `_`, `\_`, -1)
+	if b.dialect.Name() == d.DriverMssql {
+		s = strings.Replace(s, `[`, `\[`, -1)
+	}
+	return s
+}
+
+const (
+	fulltext = "fulltext"
+	// paramMarker is the parameter of the full-text sql of the dialect, which is split into the parts of a condition
+	paramMarker = "\x00"
+)
+
+// fulltext returns the full-text condition of column, or the contain condition if the dialect does not support full-text search.
+func (b *filterBuilder) fulltext(column string, text string) *condition {
+	f, ok := b.dialect.(d.FullText)
+	if !ok {
+		return b.like(column, "%"+b.escapeLike(text)+"%")
+	}
+	query := f.FullTextQuery(text)
+	if len(query) == 0 {
+		return nil
+	}
+	if b.rank == nil {
+		if order := f.FullTextOrder(column, paramMarker); len(order) > 0 {
+			b.rank = withParams(strings.Split(order, paramMarker), query)
+		}
+	}
+	return withParams(strings.Split(f.FullText(column, paramMarker), paramMarker), query)
+}
+
+// withParams returns the condition of parts, of which each parameter is value.
+func withParams(parts []string, value interface{}) *condition {
+	values := make([]interface{}, len(parts)-1)
+	for i := range values {
+		values[i] = value
+	}
+	return &condition{parts: parts, values: values}
+}
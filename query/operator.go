@@ -0,0 +1,75 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Null is a tri-state filter of a nullable column: IsNull matches the null values, NotNull matches the other values, and an empty Null matches all rows.
+type Null string
+
+const (
+	IsNull  Null = "null"
+	NotNull Null = "notnull"
+)
+
+// The operators of the operator tag, such as `operator:"<>"`.
+const (
+	notEqual   = "<>"
+	notLike    = "notlike"
+	notIn      = "notin"
+	isNull     = "isnull"
+	isNotNull  = "isnotnull"
+	notBetween = "notbetween"
+)
+
+var operators = map[string]string{
+	"=":          exact,
+	"<>":         notEqual,
+	"!=":         notEqual,
+	">":          greaterThan,
+	">=":         greaterEqualThan,
+	"<":          lessThan,
+	"<=":         lessEqualThan,
+	"like":       like,
+	"notlike":    notLike,
+	"in":         in,
+	"notin":      notIn,
+	"isnull":     isNull,
+	"null":       isNull,
+	"isnotnull":  isNotNull,
+	"notnull":    isNotNull,
+	"between":    "",
+	"notbetween": notBetween,
+}
+
+// getOperator returns the operator of the operator tag of field, or an empty string if there is no operator tag.
+// The spaces and the case of the tag are ignored, so "not like" is notlike, and "IS NOT NULL" is isnotnull.
+func getOperator(field reflect.StructField) (string, error) {
+	tag, ok := field.Tag.Lookup("operator")
+	if !ok {
+		return "", nil
+	}
+	key := strings.ToLower(strings.Join(strings.Fields(tag), ""))
+	operator, exist := operators[key]
+	if !exist {
+		return "", invalidFilter("operator %q of field %s is not supported", tag, field.Name)
+	}
+	return operator, nil
+}
+
+// nullCondition returns the condition "column is null", or "column is not null" if null is false.
+func nullCondition(column string, null bool) *condition {
+	if null {
+		return &condition{parts: []string{column + " is null"}}
+	}
+	return &condition{parts: []string{column + " is not null"}}
+}
+
+// negate returns the negation of c, or nil if c is nil.
+func negate(c *condition) *condition {
+	if c == nil {
+		return nil
+	}
+	return &condition{not: true, items: []*condition{c}}
+}
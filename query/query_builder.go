@@ -1,25 +1,20 @@
 package query
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	s "github.com/core-go/search"
-	"log"
+	d "github.com/core-go/sql"
 	"reflect"
-	"strconv"
 	"strings"
 	"time"
 )
 
 const (
-	driverPostgres   = "postgres"
-	driverMysql      = "mysql"
-	driverMssql      = "mssql"
-	driverOracle     = "oracle"
-	driverSqlite3    = "sqlite3"
-	driverNotSupport = "no support"
-	desc             = "desc"
-	asc              = "asc"
+	desc = "desc"
+	asc  = "asc"
 )
 
 type Builder struct {
@@ -27,17 +22,19 @@ type Builder struct {
 	ModelType  reflect.Type
 	Driver     string
 	BuildParam func(int) string
+	// Unscoped includes the soft deleted rows
+	Unscoped bool
 }
 
 func NewBuilder(db *sql.DB, tableName string, modelType reflect.Type, options ...func(int) string) *Builder {
-	driver := getDriver(db)
+	dialect := d.GetDialect(db)
 	var build func(int) string
 	if len(options) > 0 {
 		build = options[0]
 	} else {
-		build = getBuild(db)
+		build = dialect.BuildParam
 	}
-	return NewBuilderWithDriver(tableName, modelType, driver, build)
+	return NewBuilderWithDriver(tableName, modelType, dialect.Name(), build)
 }
 func NewBuilderWithDriver(tableName string, modelType reflect.Type, driver string, buildParam func(int) string) *Builder {
 	return &Builder{TableName: tableName, ModelType: modelType, Driver: driver, BuildParam: buildParam}
@@ -82,286 +79,435 @@ func getColumnNameFromSqlBuilderTag(typeOfField reflect.StructField) *string {
 	return nil*/
 }
 func (b *Builder) BuildQuery(sm interface{}) (string, []interface{}) {
-	return Build(sm, b.TableName, b.ModelType, b.Driver, b.BuildParam)
+	return mustBuild(build(nil, sm, b.TableName, b.ModelType, b.Driver, b.BuildParam, b.Unscoped))
 }
+
+// BuildQueryContext is BuildQuery, which includes only the rows of the tenant of ctx, if the model has a tenant column.
+func (b *Builder) BuildQueryContext(ctx context.Context, sm interface{}) (string, []interface{}) {
+	return mustBuild(build(ctx, sm, b.TableName, b.ModelType, b.Driver, b.BuildParam, b.Unscoped))
+}
+
+// BuildQueryWithError is BuildQueryContext, which returns the error of an invalid search model instead of panicking; see BuildWithError.
+func (b *Builder) BuildQueryWithError(ctx context.Context, sm interface{}) (string, []interface{}, error) {
+	return withError(build(ctx, sm, b.TableName, b.ModelType, b.Driver, b.BuildParam, b.Unscoped))
+}
+
+// Build joins the conditions of the fields of sm by and. The fields tagged by the same group, such as `sql_builder:"group:owner"`, are joined by or,
+// and so are the fields of a nested filter struct tagged by `sql_builder:"or"`.
+// The keyword of the search model matches the rows of which any column tagged by keyword, such as `keyword:"prefix"`, in sm or else in modelType, matches it;
+// if the search model field is tagged by `keyword:"words"`, each word of the keyword must be matched.
+// The operator of a field can be set by the operator tag, such as `operator:"<>"`, `operator:"not like"`, `operator:"not in"` or `operator:"not between"` for a range;
+// <> negates a slice or a range, and the operators which cannot apply to the type of the field, such as > for a slice, are invalid.
+// a bool field tagged by `operator:"isnull"` or `operator:"isnotnull"` filters the null values if it is true, and a Null field filters the null or the not null values.
+// The string fields tagged by `match:"fulltext"`, and the keyword columns tagged by `keyword:"fulltext"`, are matched by the full-text search of the dialect,
+// and the rows are sorted by relevance if there is no sort.
+// Build excludes the soft deleted rows, if modelType has a soft-delete column.
+// If modelType has a tenant column, no row is matched, because there is no tenant; use BuildContext instead.
+// If sm has a *sql.Cursor, which is not nil, the query is sorted by the primary keys after the sort of sm, and starts after the row of the cursor.
+// Build panics if sm is invalid, such as for an unknown match format; use BuildWithError to return the error.
 func Build(sm interface{}, tableName string, modelType reflect.Type, driver string, buildParam func(int) string) (string, []interface{}) {
-	s1 := ""
+	return mustBuild(build(nil, sm, tableName, modelType, driver, buildParam, false))
+}
+
+// BuildContext is Build, which includes only the rows of the tenant of ctx, if modelType has a tenant column.
+func BuildContext(ctx context.Context, sm interface{}, tableName string, modelType reflect.Type, driver string, buildParam func(int) string) (string, []interface{}) {
+	return mustBuild(build(ctx, sm, tableName, modelType, driver, buildParam, false))
+}
+
+// BuildUnscoped is Build, which includes the soft deleted rows.
+func BuildUnscoped(sm interface{}, tableName string, modelType reflect.Type, driver string, buildParam func(int) string) (string, []interface{}) {
+	return mustBuild(build(nil, sm, tableName, modelType, driver, buildParam, true))
+}
+
+// BuildWithError is BuildContext, which returns an error instead of panicking if sm is invalid:
+// the error wraps sql.ErrInvalidFilter, and describes the invalid field, or it is sql.ErrInvalidCursor.
+// It returns sql.ErrTenantRequired if modelType has a tenant column and ctx has no tenant.
+func BuildWithError(ctx context.Context, sm interface{}, tableName string, modelType reflect.Type, driver string, buildParam func(int) string) (string, []interface{}, error) {
+	return withError(build(ctx, sm, tableName, modelType, driver, buildParam, false))
+}
+
+// mustBuild panics with the error of an invalid search model, which is returned by SearchBuilder.Search.
+// Without the tenant, the query matches no row.
+func mustBuild(query string, params []interface{}, err error) (string, []interface{}) {
+	if err != nil && err != d.ErrTenantRequired {
+		panic(err)
+	}
+	return query, params
+}
+
+// withError returns no query if there is an error.
+func withError(query string, params []interface{}, err error) (string, []interface{}, error) {
+	if err != nil {
+		return "", nil, err
+	}
+	return query, params, nil
+}
+
+// invalidFilter returns the error of an invalid field of a search model.
+func invalidFilter(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{d.ErrInvalidFilter}, args...)...)
+}
+func build(ctx context.Context, sm interface{}, tableName string, modelType reflect.Type, driver string, buildParam func(int) string, unscoped bool) (string, []interface{}, error) {
+	dialect, ok := d.GetDialectByName(driver)
+	if !ok {
+		dialect = d.DefaultDialect{}
+	}
+	b := &filterBuilder{modelType: modelType, driver: driver, dialect: dialect, table: d.QuoteName(dialect, tableName)}
+	value := reflect.Indirect(reflect.ValueOf(sm))
+	conditions := b.build(value, " and ")
+	if len(b.keyword) > 0 {
+		if c := b.keywordCondition(b.keyword, b.words); c != nil {
+			conditions.add(c)
+		}
+	}
+	if b.err != nil {
+		return "", nil, b.err
+	}
+
 	rawConditions := make([]string, 0)
 	queryValues := make([]interface{}, 0)
-	rawJoin := make([]string, 0)
-	sortString := ""
-	fields := make([]string, 0)
-	var keyword string
-	var keywordFormat map[string]string
-	keywordFormat = map[string]string{
-		"prefix":  "?%",
-		"contain": "%?%",
-		"equal":   "?",
+	for _, c := range conditions.items {
+		rawConditions = append(rawConditions, c.render(buildParam, &queryValues))
+	}
+	s1 := b.sql
+	if len(b.joins) > 0 {
+		s1 = s1 + " " + strings.Join(b.joins, " ")
+	}
+	sortString := b.sortString
+	if b.cursor != nil {
+		keys := d.BuildSortKeys(b.sort, modelType)
+		sortString = d.BuildKeysetSort(keys, dialect, tableName)
+		if len(*b.cursor) > 0 {
+			if values, err := d.DecodeCursor(*b.cursor, keys, modelType); err == nil {
+				condition, params := d.BuildKeysetCondition(keys, values, dialect, buildParam, len(queryValues), tableName)
+				rawConditions = append(rawConditions, condition)
+				queryValues = append(queryValues, params...)
+			} else {
+				return "", nil, err
+			}
+		}
+	}
+	if !unscoped {
+		if softDelete := d.FindSoftDelete(modelType); softDelete != nil {
+			rawConditions = append(rawConditions, softDelete.Condition(dialect, tableName))
+		}
+	}
+	var err error
+	if t := d.FindTenant(modelType); t != nil {
+		if tenant := d.GetTenant(ctx); tenant != nil {
+			rawConditions = append(rawConditions, t.Condition(dialect, buildParam(len(queryValues)+1), tableName))
+			queryValues = append(queryValues, tenant)
+		} else {
+			rawConditions = append(rawConditions, "1 = 0")
+			err = d.ErrTenantRequired
+		}
+	}
+	if len(sortString) == 0 && b.rank != nil && b.cursor == nil {
+		// the parameters of the relevance follow the parameters of all the conditions, because the order by clause follows the where clause
+		sortString = ` order by ` + b.rank.render(buildParam, &queryValues)
 	}
+	if len(rawConditions) > 0 {
+		s2 := s1 + ` where ` + strings.Join(rawConditions, " AND ") + sortString
+		return s2, queryValues, err
+	}
+	s3 := s1 + sortString
+	return s3, queryValues, err
+}
 
-	value := reflect.Indirect(reflect.ValueOf(sm))
+// filterBuilder builds the conditions of a search model, and the select statement, the joins and the sort of its *search.SearchModel.
+type filterBuilder struct {
+	modelType  reflect.Type
+	driver     string
+	dialect    d.Dialect
+	table      string
+	sql        string
+	joins      []string
+	sort       string
+	sortString string
+	cursor     *d.Cursor
+	// keyword is the keyword of the search model, which is split into words if its field is tagged by `keyword:"words"`
+	keyword        string
+	words          bool
+	keywordColumns []keywordColumn
+	// rank is the order by the relevance of the first full-text condition, if there is no sort
+	rank *condition
+	// err is the first error of the search model
+	err error
+}
+
+// fail records err, if it is the first error of the search model, and returns no condition.
+func (b *filterBuilder) fail(err error) *condition {
+	if b.err == nil {
+		b.err = err
+	}
+	return nil
+}
+
+// build returns the conditions of the fields of value, which is a search model or a nested filter, joined by op.
+// The conditions of the fields of the same group, which is tagged by `sql_builder:"group:name"`, are joined by or.
+func (b *filterBuilder) build(value reflect.Value, op string) *condition {
+	conditions := &condition{op: op, items: make([]*condition, 0)}
+	groups := make(map[string]*condition)
 	typeOfValue := value.Type()
 	numField := value.NumField()
-	marker := 0
-
 	for i := 0; i < numField; i++ {
 		field := value.Field(i)
-		kind := field.Kind()
+		typeOfField := typeOfValue.Field(i)
+		if !field.CanInterface() {
+			continue
+		}
 		x := field.Interface()
-		typeOfField := value.Type().Field(i)
-		param := buildParam(marker + 1)
-
 		if v, ok := x.(*s.SearchModel); ok {
-			if len(v.Fields) > 0 {
-				for _, key := range v.Fields {
-					i, _, columnName := getFieldByJson(modelType, key)
-					if len(columnName) < 0 {
-						fields = fields[len(fields):]
-						break
-					} else if i == -1 {
-						columnName = strings.ToLower(key) // injection
-					}
-					fields = append(fields, columnName)
-				}
-			}
-			if len(fields) > 0 {
-				s1 = `select ` + strings.Join(fields, ",") + ` from ` + tableName
-			} else {
-				columns := getColumnsSelect(modelType)
-				if len(columns) > 0 {
-					s1 = `select  ` + strings.Join(columns, ",") + ` from ` + tableName
-				} else {
-					s1 = `select * from ` + tableName
-				}
-			}
-			if len(v.Sort) > 0 {
-				sortString = buildSort(v.Sort, modelType)
+			if v != nil {
+				b.searchModel(v, typeOfValue, conditions)
+				b.keyword = strings.TrimSpace(v.Keyword)
+				b.words = typeOfField.Tag.Get("keyword") == "words"
 			}
+			continue
 		}
-
-		columnName, existCol := getColumnName(value.Type(), typeOfField.Name)
-		if !existCol {
-			columnName, _ = getColumnName(modelType, typeOfField.Name)
-		}
-
-		columnNameFromSqlBuilderTag := getColumnNameFromSqlBuilderTag(typeOfField)
-		if columnNameFromSqlBuilderTag != nil {
-			columnName = *columnNameFromSqlBuilderTag
+		if c, ok := x.(*d.Cursor); ok {
+			b.cursor = c
+			continue
 		}
-
 		joinFromSqlBuilderTag := getJoinFromSqlBuilderTag(typeOfField)
 		if joinFromSqlBuilderTag != nil {
-			rawJoin = append(rawJoin, *joinFromSqlBuilderTag)
+			b.joins = append(b.joins, *joinFromSqlBuilderTag)
 		}
-
-		ps := false
-		var value2 string
-		if kind == reflect.Ptr {
-			if field.IsNil() {
-				continue
-			}
-			s0, ok0 := x.(*string)
-			if ok0 {
-				if s0 == nil || len(*s0) == 0 {
-					continue
-				}
-				ps = true
-				value2 = *s0
-			}
-			field = field.Elem()
-			kind = field.Kind()
+		if k, ok := b.keywordColumn(typeOfField, typeOfValue); ok {
+			b.keywordColumns = append(b.keywordColumns, k)
 		}
-		s0, ok0 := x.(string)
-		if ok0 {
-			if len(s0) == 0 {
+		c := b.condition(field, typeOfValue, typeOfField)
+		if c == nil {
+			continue
+		}
+		if name := getStringFromTag(typeOfField, "sql_builder", "group:"); name != nil && len(*name) > 0 {
+			if g, ok := groups[*name]; ok {
+				g.add(c)
 				continue
 			}
-			value2 = s0
+			g := group(" or ", c)
+			groups[*name] = g
+			conditions.items = append(conditions.items, g)
+			continue
 		}
-		if v, ok := x.(*s.SearchModel); ok {
-			if len(v.Excluding) > 0 {
-				for key, val := range v.Excluding {
-					index, _, columnName := getFieldByJson(value.Type(), key)
-					if index == -1 || columnName == "" {
-						log.Panic("column name not found")
-					}
-					if len(val) > 0 {
-						format := fmt.Sprintf("(%s)", buildParametersFrom(marker, len(val), buildParam))
-						marker += len(val) - 1
-						rawConditions = append(rawConditions, fmt.Sprintf("%s NOT IN %s", columnName, format))
-						queryValues = extractArray(queryValues, val)
-					}
-				}
-			} else if len(v.Keyword) > 0 {
-				keyword = strings.TrimSpace(v.Keyword)
-			}
+		conditions.add(c)
+	}
+	return conditions
+}
+
+// searchModel builds the select statement and the sort of sm, and adds the conditions of its excluding values.
+func (b *filterBuilder) searchModel(sm *s.SearchModel, filterType reflect.Type, conditions *condition) {
+	fields := make([]string, 0)
+	for _, key := range sm.Fields {
+		i, _, columnName := getFieldByJson(b.modelType, key)
+		if i == -1 || len(columnName) == 0 {
 			continue
-		} else if ps || kind == reflect.String {
-			var searchValue bool
-			if field.Len() > 0 {
-				const defaultKey = "contain"
-				if key, ok := typeOfValue.Field(i).Tag.Lookup("match"); ok {
-					if format, exist := keywordFormat[key]; exist {
-						searchValue = true
-						//if sql == "mysql" {
-						//	value2 = EscapeString(value2)
-						//} else if sql == "postgres" || sql == "mssql" {
-						//	value2 = EscapeStringForSelect(value2)
-						//}
-						value2 = func(format, s string) string {
-							return strings.Replace(format, "?", s, -1)
-						}(format, value2)
-						//value2 = value2 + `%`
-						//rawConditions = append(rawConditions, fmt.Sprintf("%s %s ?", columnName, like))
-						queryValues = append(queryValues, value2)
-					} else {
-						log.Panicf("match not support \"%v\" format\n", key)
-					}
-				} else if format, exist := keywordFormat[defaultKey]; exist {
-					searchValue = true
-					//if sql == "mysql" {
-					//	value2 = EscapeString(value2)
-					//} else if sql == "postgres" || sql == "mssql" {
-					//	value2 = EscapeStringForSelect(value2)
-					//}
-					//value2 = `%` + value2 + `%`
-					value2 = func(format, s string) string {
-						return strings.Replace(format, "?", s, -1)
-					}(format, value2)
-					//rawConditions = append(rawConditions, fmt.Sprintf("%s %s ?", columnName, like))
-					queryValues = append(queryValues, value2)
-				} else {
-					searchValue = true
-					value2, valid := x.(string)
-					if !valid {
-						log.Panicf("invalid data \"%v\" \n", x)
-					}
-					value2 = value2 + `%`
-					//rawConditions = append(rawConditions, fmt.Sprintf("%s %s ?", columnName, like))
-					queryValues = append(queryValues, value2)
-				}
-			} else if len(keyword) > 0 {
-				if key, ok := typeOfValue.Field(i).Tag.Lookup("keyword"); ok {
-					if format, exist := keywordFormat[key]; exist {
-						//if sql == "mysql" {
-						//	keyword = EscapeString(keyword)
-						//} else if sql == "postgres" || sql == "mssql" {
-						//	keyword = EscapeStringForSelect(keyword)
-						//}
-						if format == `?%` {
-							keyword = keyword + `%`
-						} else if format == `%?%` {
-							keyword = `%` + keyword + `%`
-						} else {
-							log.Panicf("keyword not support \"%v\" format\n", key)
-						}
+		}
+		fields = append(fields, d.QuoteName(b.dialect, columnName))
+	}
+	if len(fields) > 0 {
+		b.sql = `select ` + strings.Join(fields, ",") + ` from ` + b.table
+	} else {
+		columns := getColumnsSelect(b.modelType, b.dialect)
+		if len(columns) > 0 {
+			b.sql = `select  ` + strings.Join(columns, ",") + ` from ` + b.table
+		} else {
+			b.sql = `select * from ` + b.table
+		}
+	}
+	if len(sm.Sort) > 0 {
+		b.sort = sm.Sort
+		b.sortString = buildSort(sm.Sort, b.modelType, b.dialect)
+	}
+	for key, val := range sm.Excluding {
+		index, _, columnName := getFieldByJson(filterType, key)
+		if index == -1 || columnName == "" {
+			b.fail(invalidFilter("excluded field %q is not found", key))
+			continue
+		}
+		if len(val) > 0 {
+			conditions.add(list(d.QuoteName(b.dialect, columnName), "NOT IN", extractArray(nil, val)))
+		}
+	}
+}
 
-						queryValues = append(queryValues, keyword)
-					} else {
-						log.Panicf("keyword not support \"%v\" format\n", key)
-					}
-				}
-			}
-			if searchValue {
-				if driver == driverPostgres { // "postgres"
-					rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, `ilike`, param))
-				} else {
-					rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, like, param))
-				}
-				marker++
-			}
-		} else if dateRange, ok := x.(s.DateRange); ok {
-			rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, greaterEqualThan, param))
-			queryValues = append(queryValues, dateRange.StartDate)
-			var eDate = dateRange.EndDate.Add(time.Hour * 24)
-			dateRange.EndDate = &eDate
-			rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, lessThan, param))
-			queryValues = append(queryValues, dateRange.EndDate)
-			marker += 2
-		} else if dateRange, ok := x.(*s.DateRange); ok && dateRange != nil {
-			rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, greaterEqualThan, param))
-			queryValues = append(queryValues, dateRange.StartDate)
-			var eDate = dateRange.EndDate.Add(time.Hour * 24)
-			dateRange.EndDate = &eDate
-			rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, lessThan, param))
-			queryValues = append(queryValues, dateRange.EndDate)
-			marker += 2
-		} else if dateTime, ok := x.(s.TimeRange); ok {
-			rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, greaterEqualThan, param))
-			queryValues = append(queryValues, dateTime.StartTime)
-			var eDate = dateTime.EndTime.Add(time.Hour * 24)
-			dateTime.EndTime = &eDate
-			rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, lessThan, param))
-			queryValues = append(queryValues, dateTime.EndTime)
-			marker += 2
-		} else if dateTime, ok := x.(*s.TimeRange); ok && dateTime != nil {
-			rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, greaterEqualThan, param))
-			queryValues = append(queryValues, dateTime.StartTime)
-			var eDate = dateTime.EndTime.Add(time.Hour * 24)
-			dateTime.EndTime = &eDate
-			rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, lessThan, param))
-			queryValues = append(queryValues, dateTime.EndTime)
-			marker += 2
-		} else if numberRange, ok := x.(s.NumberRange); ok {
-			if numberRange.Min != nil {
-				rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, greaterEqualThan, param))
-				queryValues = append(queryValues, numberRange.Min)
-				marker++
-			} else if numberRange.Lower != nil {
-				rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, greaterThan, param))
-				queryValues = append(queryValues, numberRange.Lower)
-				marker++
-			}
-			if numberRange.Max != nil {
-				rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, lessEqualThan, param))
-				queryValues = append(queryValues, numberRange.Max)
-				marker++
-			} else if numberRange.Upper != nil {
-				rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, lessThan, param))
-				queryValues = append(queryValues, numberRange.Upper)
-				marker++
+// condition returns the condition of field, or nil if field is empty.
+// A struct field, which is not a range, a time or a driver.Valuer, is a nested filter, of which the conditions are joined by and, or by or if it is tagged by `sql_builder:"or"`.
+func (b *filterBuilder) condition(field reflect.Value, typeOfValue reflect.Type, typeOfField reflect.StructField) *condition {
+	x := field.Interface()
+	columnName, existCol := getColumnName(typeOfValue, typeOfField.Name)
+	if !existCol {
+		columnName, _ = getColumnName(b.modelType, typeOfField.Name)
+	}
+	columnNameFromSqlBuilderTag := getColumnNameFromSqlBuilderTag(typeOfField)
+	if columnNameFromSqlBuilderTag != nil {
+		columnName = *columnNameFromSqlBuilderTag
+	} else if len(columnName) > 0 {
+		columnName = d.QuoteName(b.dialect, columnName)
+	}
+
+	operator, err := getOperator(typeOfField)
+	if err != nil {
+		return b.fail(err)
+	}
+	kind := field.Kind()
+	if kind == reflect.Ptr {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+		kind = field.Kind()
+	}
+	if operator == isNull || operator == isNotNull {
+		// the condition of a bool field applies if it is true, or the opposite condition if it is a false *bool
+		isTrue, ok := field.Interface().(bool)
+		if !ok {
+			return b.fail(invalidFilter("operator %s of field %s requires a bool field, not %s", operator, typeOfField.Name, field.Type()))
+		}
+		if !isTrue && typeOfField.Type.Kind() != reflect.Ptr {
+			return nil
+		}
+		return nullCondition(columnName, isTrue == (operator == isNull))
+	}
+	switch v := field.Interface().(type) {
+	case Null:
+		switch v {
+		case IsNull, NotNull:
+			return nullCondition(columnName, v == IsNull)
+		case "":
+			return nil
+		}
+		return b.fail(invalidFilter("null filter %q of field %s is not %q or %q", v, typeOfField.Name, IsNull, NotNull))
+	case s.DateRange:
+		return b.between(timeRange(columnName, v.StartDate, v.EndDate), operator, typeOfField)
+	case s.TimeRange:
+		return b.between(timeRange(columnName, v.StartTime, v.EndTime), operator, typeOfField)
+	case s.NumberRange:
+		return b.between(numberRange(columnName, v), operator, typeOfField)
+	}
+	if operator == notBetween {
+		return b.fail(invalidFilter("operator not between of field %s requires a range field", typeOfField.Name))
+	}
+	if _, ok := field.Interface().(time.Time); ok {
+		return compare(columnName, comparison(operator), x)
+	}
+	switch kind {
+	case reflect.String:
+		value := field.String()
+		if len(value) == 0 {
+			return nil
+		}
+		if operator != "" && operator != like && operator != notLike {
+			return compare(columnName, comparison(operator), value)
+		}
+		format := keywordFormat["contain"]
+		if key, ok := typeOfField.Tag.Lookup("match"); ok {
+			if key == fulltext {
+				return b.fulltext(columnName, value)
 			}
-		} else if numberRange, ok := x.(*s.NumberRange); ok && numberRange != nil {
-			if numberRange.Min != nil {
-				rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, greaterEqualThan, param))
-				queryValues = append(queryValues, numberRange.Min)
-				marker++
-			} else if numberRange.Lower != nil {
-				rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, greaterThan, param))
-				queryValues = append(queryValues, numberRange.Lower)
-				marker++
+			f, exist := keywordFormat[key]
+			if !exist {
+				return b.fail(invalidFilter("match format %q of field %s is not supported", key, typeOfField.Name))
 			}
-			if numberRange.Max != nil {
-				rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, lessEqualThan, param))
-				queryValues = append(queryValues, numberRange.Max)
-				marker++
-			} else if numberRange.Upper != nil {
-				rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, lessThan, param))
-				queryValues = append(queryValues, numberRange.Upper)
-				marker++
+			format = f
+		}
+		op := like
+		if b.driver == d.DriverPostgres {
+			op = `ilike`
+		}
+		if operator == notLike {
+			op = "not " + op
+		}
+		return compare(columnName, op, strings.Replace(format, "?", value, -1))
+	case reflect.Slice:
+		if field.Len() == 0 {
+			return nil
+		}
+		switch operator {
+		case "", exact, in:
+			return list(columnName, in, extractArray(nil, x))
+		case notEqual, notIn:
+			return list(columnName, "not in", extractArray(nil, x))
+		}
+		return b.fail(invalidFilter("operator %s of field %s is not supported for a slice", operator, typeOfField.Name))
+	case reflect.Struct:
+		if _, ok := x.(driver.Valuer); !ok {
+			op := " and "
+			if hasSqlBuilderTag(typeOfField, "or") {
+				op = " or "
 			}
-		} else if kind == reflect.Slice {
-			if field.Len() > 0 {
-				format := fmt.Sprintf("(%s)", buildParametersFrom(marker, field.Len(), buildParam))
-				rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, in, format))
-				queryValues = extractArray(queryValues, x)
-				marker += field.Len()
+			nested := b.build(field, op)
+			if len(nested.items) == 0 {
+				return nil
 			}
-		} else {
-			rawConditions = append(rawConditions, fmt.Sprintf("%s %s %s", columnName, exact, param))
-			queryValues = append(queryValues, x)
+			return nested
 		}
 	}
+	return compare(columnName, comparison(operator), x)
+}
 
-	if len(rawJoin) > 0 {
-		s1 = s1 + " " + strings.Join(rawJoin, " ")
+// comparison returns the comparison operator of operator for a value which is not a string or a slice, which is = by default, or <> for a negation.
+func comparison(operator string) string {
+	switch operator {
+	case "", like, in:
+		return exact
+	case notIn, notLike:
+		return notEqual
 	}
-	if len(rawConditions) > 0 {
-		s2 := s1 + ` where ` + strings.Join(rawConditions, " AND ") + sortString
-		return s2, queryValues
+	return operator
+}
+
+// between returns the condition of a range, which is negated if operator is <> or notbetween.
+// The other operators, such as > or like, are not supported for a range.
+func (b *filterBuilder) between(c *condition, operator string, typeOfField reflect.StructField) *condition {
+	switch operator {
+	case "", exact:
+		return c
+	case notEqual, notBetween:
+		return negate(c)
 	}
-	s3 := s1 + sortString
-	return s3, queryValues
+	return b.fail(invalidFilter("operator %s of field %s is not supported for a range", operator, typeOfField.Name))
+}
+
+var keywordFormat = map[string]string{
+	"prefix":  "?%",
+	"contain": "%?%",
+	"equal":   "?",
+}
+
+// timeRange returns the conditions of the times from start to the day after end.
+func timeRange(columnName string, start *time.Time, end *time.Time) *condition {
+	items := make([]*condition, 0, 2)
+	if start != nil {
+		items = append(items, compare(columnName, greaterEqualThan, start))
+	}
+	if end != nil {
+		eDate := end.Add(time.Hour * 24)
+		items = append(items, compare(columnName, lessThan, &eDate))
+	}
+	return group(" and ", items...)
+}
+func numberRange(columnName string, r s.NumberRange) *condition {
+	items := make([]*condition, 0, 2)
+	if r.Min != nil {
+		items = append(items, compare(columnName, greaterEqualThan, r.Min))
+	} else if r.Lower != nil {
+		items = append(items, compare(columnName, greaterThan, r.Lower))
+	}
+	if r.Max != nil {
+		items = append(items, compare(columnName, lessEqualThan, r.Max))
+	} else if r.Upper != nil {
+		items = append(items, compare(columnName, lessThan, r.Upper))
+	}
+	return group(" and ", items...)
+}
+func hasSqlBuilderTag(typeOfField reflect.StructField, key string) bool {
+	for _, property := range strings.Split(typeOfField.Tag.Get("sql_builder"), ";") {
+		if strings.TrimSpace(property) == key {
+			return true
+		}
+	}
+	return false
 }
 
 func extractArray(values []interface{}, field interface{}) []interface{} {
@@ -421,7 +567,7 @@ func getColumnName(modelType reflect.Type, fieldName string) (col string, colExi
 	//return gorm.ToColumnName(fieldName), false
 	return fieldName, false
 }
-func getColumnsSelect(modelType reflect.Type) []string {
+func getColumnsSelect(modelType reflect.Type, dialect d.Dialect) []string {
 	numField := modelType.NumField()
 	columnNameKeys := make([]string, 0)
 	for i := 0; i < numField; i++ {
@@ -434,7 +580,7 @@ func getColumnsSelect(modelType reflect.Type) []string {
 				str2 := strings.Split(str1[i], ":")
 				for j := 0; j < len(str2); j++ {
 					if str2[j] == "column" {
-						columnName := str2[j+1]
+						columnName := d.QuoteName(dialect, str2[j+1])
 						columnNameTag := getColumnNameFromSqlBuilderTag(field)
 						if columnNameTag != nil {
 							columnName = *columnNameTag
@@ -447,19 +593,30 @@ func getColumnsSelect(modelType reflect.Type) []string {
 	}
 	return columnNameKeys
 }
-func buildSort(sortString string, modelType reflect.Type) string {
+
+// buildSort ignores the fields which are not columns of the model
+func buildSort(sortString string, modelType reflect.Type, dialect d.Dialect) string {
 	var sort = make([]string, 0)
 	sorts := strings.Split(sortString, ",")
 	for i := 0; i < len(sorts); i++ {
 		sortField := strings.TrimSpace(sorts[i])
+		if len(sortField) == 0 {
+			continue
+		}
 		fieldName := sortField
 		c := sortField[0:1]
 		if c == "-" || c == "+" {
 			fieldName = sortField[1:]
 		}
 		columnName := getColumnNameForSearch(modelType, fieldName)
+		if len(columnName) == 0 {
+			continue
+		}
 		sortType := getSortType(c)
-		sort = append(sort, columnName+" "+sortType)
+		sort = append(sort, d.QuoteName(dialect, columnName)+" "+sortType)
+	}
+	if len(sort) == 0 {
+		return ""
 	}
 	return ` order by ` + strings.Join(sort, ",")
 }
@@ -469,7 +626,7 @@ func getColumnNameForSearch(modelType reflect.Type, sortField string) string {
 	if i > -1 {
 		return column
 	}
-	return sortField // injection
+	return ""
 }
 func getSortType(sortType string) string {
 	if sortType == "-" {
@@ -478,56 +635,3 @@ func getSortType(sortType string) string {
 		return asc
 	}
 }
-
-func getDriver(db *sql.DB) string {
-	if db == nil {
-		return driverNotSupport
-	}
-	driver := reflect.TypeOf(db.Driver()).String()
-	switch driver {
-	case "*pq.Driver":
-		return driverPostgres
-	case "*godror.drv":
-		return driverOracle
-	case "*mysql.MySQLDriver":
-		return driverMysql
-	case "*mssql.Driver":
-		return driverMssql
-	case "*sqlite3.SQLiteDriver":
-		return driverSqlite3
-	default:
-		return driverNotSupport
-	}
-}
-func buildParam(i int) string {
-	return "?"
-}
-func buildOracleParam(i int) string {
-	return ":val" + strconv.Itoa(i)
-}
-func buildMsSqlParam(i int) string {
-	return "@p" + strconv.Itoa(i)
-}
-func buildDollarParam(i int) string {
-	return "$" + strconv.Itoa(i)
-}
-func getBuild(db *sql.DB) func(i int) string {
-	driver := reflect.TypeOf(db.Driver()).String()
-	switch driver {
-	case "*pq.Driver":
-		return buildDollarParam
-	case "*godror.drv":
-		return buildOracleParam
-	case "*mssql.Driver":
-		return buildMsSqlParam
-	default:
-		return buildParam
-	}
-}
-func buildParametersFrom(i int, numCol int, buildParam func(i int) string) string {
-	var arrValue []string
-	for j := 0; j < numCol; j++ {
-		arrValue = append(arrValue, buildParam(i+j+1))
-	}
-	return strings.Join(arrValue, ",")
-}
@@ -0,0 +1,214 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	s "github.com/core-go/search"
+	d "github.com/core-go/sql"
+)
+
+type user struct {
+	Id       string `json:"id" gorm:"column:id;primary_key"`
+	Name     string `json:"name" gorm:"column:name" keyword:"prefix"`
+	Email    string `json:"email" gorm:"column:email" keyword:"contain"`
+	Status   string `json:"status" gorm:"column:status"`
+	Age      int    `json:"age" gorm:"column:age"`
+	Bio      string `json:"bio" gorm:"column:bio"`
+	TenantId string `json:"tenantId" gorm:"column:tenant_id"`
+}
+type tenantUser struct {
+	Id       string `json:"id" gorm:"column:id;primary_key"`
+	Bio      string `json:"bio" gorm:"column:bio"`
+	TenantId string `json:"tenantId" gorm:"column:tenant_id;tenant"`
+}
+
+type userFilter struct {
+	*s.SearchModel
+	Name     string   `json:"name" gorm:"column:name" match:"prefix" sql_builder:"group:who"`
+	Email    string   `json:"email" gorm:"column:email" sql_builder:"group:who"`
+	Status   []string `json:"status" gorm:"column:status" operator:"notin"`
+	Age      *int     `json:"age" gorm:"column:age" operator:">="`
+	Bio      Null     `json:"bio" gorm:"column:bio"`
+	NoTenant bool     `json:"noTenant" gorm:"column:tenant_id" operator:"isnull"`
+}
+type wordsFilter struct {
+	*s.SearchModel `keyword:"words"`
+}
+type fulltextFilter struct {
+	*s.SearchModel
+	Bio  string    `json:"bio" gorm:"column:bio" match:"fulltext"`
+	Next *d.Cursor `json:"next"`
+}
+type invalidMatch struct {
+	Name string `json:"name" gorm:"column:name" match:"unknown"`
+}
+type invalidOperator struct {
+	Name string `json:"name" gorm:"column:name" operator:"~"`
+}
+
+const userColumns = `select  "id","name","email","status","age","bio","tenant_id" from "users"`
+
+func TestBuildWithError(t *testing.T) {
+	age := 18
+	tests := []struct {
+		name   string
+		sm     interface{}
+		query  string
+		params []interface{}
+	}{
+		{"group and operators", &userFilter{SearchModel: &s.SearchModel{}, Name: "a", Email: "b", Status: []string{"x", "y"}, Age: &age, Bio: NotNull, NoTenant: true},
+			userColumns + ` where ("name" ilike $1 or "email" ilike $2) AND "status" not in ($3,$4) AND "age" >= $5 AND "bio" is not null AND "tenant_id" is null`,
+			[]interface{}{"a%", "%b%", "x", "y", 18}},
+		{"keyword", &userFilter{SearchModel: &s.SearchModel{Keyword: "a_b%", Sort: "-age"}},
+			userColumns + ` where ("name" ilike $1 or "email" ilike $2) order by "age" desc`,
+			[]interface{}{`a\_b\%%`, `%a\_b\%%`}},
+		{"keyword words", &wordsFilter{SearchModel: &s.SearchModel{Keyword: "ab  cd"}},
+			userColumns + ` where ("name" ilike $1 or "email" ilike $2) AND ("name" ilike $3 or "email" ilike $4)`,
+			[]interface{}{"ab%", "%ab%", "cd%", "%cd%"}},
+		{"fulltext", &fulltextFilter{SearchModel: &s.SearchModel{}, Bio: "go sql"},
+			userColumns + ` where to_tsvector("bio") @@ plainto_tsquery($1) order by ts_rank(to_tsvector("bio"), plainto_tsquery($2)) desc`,
+			[]interface{}{"go sql", "go sql"}},
+		{"no filter", &userFilter{SearchModel: &s.SearchModel{Fields: []string{"id", "name", "unknown"}}},
+			`select "id","name" from "users"`, []interface{}{}},
+	}
+	for _, tt := range tests {
+		query, params, err := BuildWithError(context.Background(), tt.sm, "users", reflect.TypeOf(user{}), d.DriverPostgres, d.BuildDollarParam)
+		if err != nil || query != tt.query || !equalParams(params, tt.params) {
+			t.Errorf("%s: BuildWithError() = %q %v, %v, want %q %v", tt.name, query, params, err, tt.query, tt.params)
+		}
+	}
+}
+
+func TestBuildKeywordEscape(t *testing.T) {
+	tests := []struct {
+		driver     string
+		buildParam func(int) string
+		query      string
+		params     []interface{}
+	}{
+		{d.DriverPostgres, d.BuildDollarParam, ` where ("name" ilike $1 or "email" ilike $2)`, []interface{}{`x[a]\_%`, `%x[a]\_%`}},
+		{d.DriverMysql, d.BuildParam, " where (`name` like ? or `email` like ?)", []interface{}{`x[a]\_%`, `%x[a]\_%`}},
+		{d.DriverMssql, d.BuildMsSqlParam, ` where ([name] like @p1 escape '\' or [email] like @p2 escape '\')`, []interface{}{`x\[a]\_%`, `%x\[a]\_%`}},
+	}
+	for _, tt := range tests {
+		query, params, err := BuildWithError(context.Background(), &wordsFilter{SearchModel: &s.SearchModel{Keyword: "x[a]_"}}, "users", reflect.TypeOf(user{}), tt.driver, tt.buildParam)
+		if i := strings.Index(query, " where "); i >= 0 {
+			query = query[i:]
+		}
+		if err != nil || query != tt.query || !equalParams(params, tt.params) {
+			t.Errorf("%s: BuildWithError() = %q %q, %v, want %q %q", tt.driver, query, params, err, tt.query, tt.params)
+		}
+	}
+}
+
+func TestBuildWithErrorInvalid(t *testing.T) {
+	invalid := d.Cursor("!")
+	tests := []struct {
+		name      string
+		sm        interface{}
+		modelType reflect.Type
+		want      error
+	}{
+		{"match", &invalidMatch{Name: "a"}, reflect.TypeOf(user{}), d.ErrInvalidFilter},
+		{"operator", &invalidOperator{Name: "a"}, reflect.TypeOf(user{}), d.ErrInvalidFilter},
+		{"excluding", &userFilter{SearchModel: &s.SearchModel{Excluding: map[string][]string{"unknown": {"1"}}}}, reflect.TypeOf(user{}), d.ErrInvalidFilter},
+		{"cursor", &fulltextFilter{SearchModel: &s.SearchModel{}, Next: &invalid}, reflect.TypeOf(user{}), d.ErrInvalidCursor},
+		{"tenant", &fulltextFilter{SearchModel: &s.SearchModel{}}, reflect.TypeOf(tenantUser{}), d.ErrTenantRequired},
+	}
+	for _, tt := range tests {
+		query, params, err := BuildWithError(context.Background(), tt.sm, "users", tt.modelType, d.DriverPostgres, d.BuildDollarParam)
+		if !errors.Is(err, tt.want) || len(query) > 0 || params != nil {
+			t.Errorf("%s: BuildWithError() = %q %v, %v, want %v", tt.name, query, params, err, tt.want)
+		}
+	}
+}
+
+type operatorFilter struct {
+	Status   []string       `json:"status" gorm:"column:status" operator:"<>"`
+	Statuses []string       `json:"statuses" gorm:"column:status" operator:"!="`
+	Age      *int           `json:"age" gorm:"column:age" operator:"not like"`
+	Born     *time.Time     `json:"born" gorm:"column:born" operator:"not like"`
+	Ages     *s.NumberRange `json:"ages" gorm:"column:age" operator:"<>"`
+	Dates    *s.DateRange   `json:"dates" gorm:"column:born" operator:"!="`
+	Times    *s.TimeRange   `json:"times" gorm:"column:born" operator:"not between"`
+}
+type sliceLikeFilter struct {
+	Status []string `json:"status" gorm:"column:status" operator:"like"`
+}
+type sliceGreaterFilter struct {
+	Status []string `json:"status" gorm:"column:status" operator:">"`
+}
+type rangeLikeFilter struct {
+	Ages *s.NumberRange `json:"ages" gorm:"column:age" operator:"like"`
+}
+type rangeGreaterFilter struct {
+	Dates *s.DateRange `json:"dates" gorm:"column:born" operator:">="`
+}
+
+func TestBuildNegation(t *testing.T) {
+	age, min, max := 18, 1.0, 2.0
+	born := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+	next := born.Add(24 * time.Hour)
+	tests := []struct {
+		name   string
+		sm     interface{}
+		query  string
+		params []interface{}
+	}{
+		{"<> on a slice", &operatorFilter{Status: []string{"a", "b"}}, ` where "status" not in ($1,$2)`, []interface{}{"a", "b"}},
+		{"!= on a slice", &operatorFilter{Statuses: []string{"a"}}, ` where "status" not in ($1)`, []interface{}{"a"}},
+		{"not like on a number", &operatorFilter{Age: &age}, ` where "age" <> $1`, []interface{}{18}},
+		{"not like on a time", &operatorFilter{Born: &born}, ` where "born" <> $1`, []interface{}{born}},
+		{"<> on a number range", &operatorFilter{Ages: &s.NumberRange{Min: &min, Max: &max}}, ` where not ("age" >= $1 and "age" <= $2)`, []interface{}{1.0, 2.0}},
+		{"!= on a date range", &operatorFilter{Dates: &s.DateRange{StartDate: &born}}, ` where not ("born" >= $1)`, []interface{}{born}},
+		{"not between on a time range", &operatorFilter{Times: &s.TimeRange{EndTime: &born}}, ` where not ("born" < $1)`, []interface{}{next}},
+	}
+	for _, tt := range tests {
+		query, params, err := BuildWithError(context.Background(), tt.sm, "users", reflect.TypeOf(user{}), d.DriverPostgres, d.BuildDollarParam)
+		// the filter has no search model, so the query has the where clause only
+		if err != nil || query != tt.query || !equalParams(params, tt.params) {
+			t.Errorf("%s: BuildWithError() = %q %v, %v, want %q %v", tt.name, query, params, err, tt.query, tt.params)
+		}
+	}
+	invalid := []interface{}{
+		&sliceLikeFilter{Status: []string{"a"}},
+		&sliceGreaterFilter{Status: []string{"a"}},
+		&rangeLikeFilter{Ages: &s.NumberRange{Min: &min}},
+		&rangeGreaterFilter{Dates: &s.DateRange{StartDate: &born}},
+	}
+	for _, sm := range invalid {
+		if _, _, err := BuildWithError(context.Background(), sm, "users", reflect.TypeOf(user{}), d.DriverPostgres, d.BuildDollarParam); !errors.Is(err, d.ErrInvalidFilter) {
+			t.Errorf("BuildWithError(%T) = %v, want ErrInvalidFilter", sm, err)
+		}
+	}
+}
+
+func TestBuildRankAfterConditions(t *testing.T) {
+	ctx := d.WithTenant(context.Background(), "t1")
+	query, params, err := BuildWithError(ctx, &fulltextFilter{SearchModel: &s.SearchModel{}, Bio: "go"}, "users", reflect.TypeOf(tenantUser{}), d.DriverPostgres, d.BuildDollarParam)
+	want := `select  "id","bio","tenant_id" from "users" where to_tsvector("bio") @@ plainto_tsquery($1) AND "users"."tenant_id" = $2 order by ts_rank(to_tsvector("bio"), plainto_tsquery($3)) desc`
+	if err != nil || query != want || !equalParams(params, []interface{}{"go", "t1", "go"}) {
+		t.Errorf("BuildWithError() = %q %v, %v, want %q", query, params, err, want)
+	}
+}
+
+// equalParams compares the parameters, of which the pointers are compared by their values.
+func equalParams(params []interface{}, want []interface{}) bool {
+	if len(params) != len(want) {
+		return false
+	}
+	for i, p := range params {
+		if v := reflect.ValueOf(p); v.Kind() == reflect.Ptr && !v.IsNil() {
+			p = v.Elem().Interface()
+		}
+		if !reflect.DeepEqual(p, want[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package sql
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrInvalidIdentifier = errors.New("invalid identifier")
+
+var plainIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// IsPlainIdentifier returns true if name can be used without quotes.
+// The dialects fold plain identifiers like the database folds them without quotes, so quoting never changes the referenced table or column.
+func IsPlainIdentifier(name string) bool {
+	return plainIdentifier.MatchString(name)
+}
+
+// QuoteIdentifier quotes a table or column name with d. A schema-qualified name such as "sales.orders" is quoted part by part.
+// It returns ErrInvalidIdentifier if a part is empty, is not valid UTF-8 or contains a control character, which cannot be quoted safely.
+// If d is nil, the name is validated and returned as is.
+func QuoteIdentifier(d Dialect, name string) (string, error) {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if len(part) == 0 || !utf8.ValidString(part) || strings.IndexFunc(part, unicode.IsControl) >= 0 {
+			return "", &IdentifierError{Name: name}
+		}
+		if d != nil {
+			parts[i] = d.Quote(part)
+		}
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// QuoteName is QuoteIdentifier, but it panics on an invalid identifier, like the other errors of the model definition.
+func QuoteName(d Dialect, name string) string {
+	s, err := QuoteIdentifier(d, name)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+func QuoteNames(d Dialect, names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = QuoteName(d, name)
+	}
+	return quoted
+}
+
+type IdentifierError struct {
+	Name string
+}
+
+func (e *IdentifierError) Error() string {
+	return "invalid identifier " + strings.ToValidUTF8(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return '?'
+		}
+		return r
+	}, e.Name), "?")
+}
+func (e *IdentifierError) Is(target error) bool {
+	return target == ErrInvalidIdentifier
+}
+
+func getDialect(options []Dialect) Dialect {
+	if len(options) > 0 {
+		return options[0]
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package sql
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		name    string
+		want    string
+	}{
+		{PostgresDialect{}, "Users", `"users"`},
+		{PostgresDialect{}, "sales.Orders", `"sales"."orders"`},
+		{PostgresDialect{}, `my "table"`, `"my ""table"""`},
+		{PostgresDialect{}, "Mixed Case", `"Mixed Case"`},
+		{MySQLDialect{}, "order", "`order`"},
+		{MySQLDialect{}, "a`b", "`a``b`"},
+		{MssqlDialect{}, "dbo.users", "[dbo].[users]"},
+		{MssqlDialect{}, "a]b", "[a]]b]"},
+		{OracleDialect{}, "users", `"USERS"`},
+		{SqliteDialect{}, "users", `"users"`},
+		{nil, "sales.orders", "sales.orders"},
+	}
+	for _, tt := range tests {
+		if got, err := QuoteIdentifier(tt.dialect, tt.name); err != nil || got != tt.want {
+			t.Errorf("QuoteIdentifier(%T, %q) = %q, %v, want %q", tt.dialect, tt.name, got, err, tt.want)
+		}
+	}
+	for _, name := range []string{"", "sales.", ".orders", "a\x00b", "a\nb", "\xff"} {
+		if _, err := QuoteIdentifier(PostgresDialect{}, name); !errors.Is(err, ErrInvalidIdentifier) {
+			t.Errorf("QuoteIdentifier(%q) = %v, want ErrInvalidIdentifier", name, err)
+		}
+	}
+}
+
+func TestQuoteNamePanics(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("QuoteName() of an invalid identifier does not panic")
+		}
+	}()
+	QuoteName(PostgresDialect{}, "a\x00b")
+}
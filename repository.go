@@ -0,0 +1,295 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type Repository[T any, K comparable] struct {
+	Database    *sql.DB
+	BuildParam  func(i int) string
+	Mapper      Mapper
+	table       string
+	modelType   reflect.Type
+	keys        []string
+	jsonKeys    []string
+	keyIndexes  map[string]int
+	fieldsIndex map[string]int
+	tenant      *Tenant
+	softDelete  *SoftDelete
+	unscoped    bool
+	Audit       *Audit
+}
+
+func NewRepository[T any, K comparable](db *sql.DB, tableName string, options ...Mapper) *Repository[T, K] {
+	var mapper Mapper
+	if len(options) >= 1 {
+		mapper = options[0]
+	}
+	return NewSqlRepository[T, K](db, tableName, mapper)
+}
+func NewSqlRepository[T any, K comparable](db *sql.DB, tableName string, mapper Mapper, options ...func(i int) string) *Repository[T, K] {
+	var buildParam func(i int) string
+	if len(options) > 0 && options[0] != nil {
+		buildParam = options[0]
+	} else {
+		buildParam = GetBuild(db)
+	}
+	var t T
+	modelType := reflect.TypeOf(t)
+	if modelType.Kind() != reflect.Struct {
+		panic("model must be a struct")
+	}
+	keys, jsonKeys := FindPrimaryKeys(modelType)
+	if len(keys) == 0 {
+		panic(fmt.Sprintf("%s has no primary_key", modelType.Name()))
+	}
+	fieldsIndex, er0 := GetColumnIndexes(modelType)
+	if er0 != nil {
+		panic(er0)
+	}
+	var k K
+	keyIndexes, er1 := MapKeyIndexes(modelType, reflect.TypeOf(k), keys)
+	if er1 != nil {
+		panic(er1)
+	}
+	return &Repository[T, K]{Database: db, BuildParam: buildParam, Mapper: mapper, table: tableName, modelType: modelType, keys: keys, jsonKeys: jsonKeys, keyIndexes: keyIndexes, fieldsIndex: fieldsIndex, tenant: FindTenant(modelType), softDelete: FindSoftDelete(modelType), Audit: NewAudit(GetAuditUser(db))}
+}
+
+// MapKeyIndexes maps each primary key column of modelType to the field index of keyType.
+// keyType can be a scalar when there is only one primary key, or a struct with one field per primary key,
+// matched by gorm column, then json name, then field name.
+func MapKeyIndexes(modelType reflect.Type, keyType reflect.Type, keys []string) (map[string]int, error) {
+	if keyType.Kind() != reflect.Struct {
+		if len(keys) != 1 {
+			return nil, fmt.Errorf("%s has %d primary keys, the key type must be a struct", modelType.Name(), len(keys))
+		}
+		return nil, nil
+	}
+	jsonColumns := FindJsonName(modelType)
+	indexes := make(map[string]int)
+	for i := 0; i < keyType.NumField(); i++ {
+		field := keyType.Field(i)
+		column, ok := FindTag(field.Tag.Get("gorm"), "column")
+		if !ok {
+			if tag, ok1 := field.Tag.Lookup("json"); ok1 {
+				column, ok = jsonColumns[strings.Split(tag, ",")[0]]
+			}
+		}
+		if !ok {
+			j := FindFieldIndex(modelType, field.Name)
+			if j >= 0 {
+				column, ok = GetColumnNameByIndex(modelType, j)
+			}
+		}
+		if ok {
+			indexes[column] = i
+		}
+	}
+	for _, key := range keys {
+		if _, ok := indexes[key]; !ok {
+			return nil, fmt.Errorf("%s does not have a field for primary key %s", keyType.Name(), key)
+		}
+	}
+	return indexes, nil
+}
+
+func (s *Repository[T, K]) Keys() []string {
+	return s.jsonKeys
+}
+
+func (s *Repository[T, K]) BuildKeyMap(id K) map[string]interface{} {
+	if s.keyIndexes == nil {
+		return map[string]interface{}{s.keys[0]: id}
+	}
+	v := reflect.ValueOf(id)
+	ids := make(map[string]interface{})
+	for _, key := range s.keys {
+		ids[key] = v.Field(s.keyIndexes[key]).Interface()
+	}
+	return ids
+}
+
+func (s *Repository[T, K]) buildWhere(ctx context.Context, id K) (string, []interface{}, error) {
+	ids := s.BuildKeyMap(id)
+	conditions := make([]string, 0)
+	values := make([]interface{}, 0)
+	for i, key := range s.keys {
+		conditions = append(conditions, fmt.Sprintf("%s = %s", QuoteName(GetDialect(s.Database), key), s.BuildParam(i+1)))
+		values = append(values, ids[key])
+	}
+	return s.scope(ctx, conditions, values)
+}
+
+// Unscoped returns a copy of the repository, which loads the soft deleted rows and deletes the rows permanently.
+func (s *Repository[T, K]) Unscoped() *Repository[T, K] {
+	r := *s
+	r.unscoped = true
+	return &r
+}
+
+// scope appends the condition of the rows which are not soft deleted, and the condition of the tenant of ctx, to conditions.
+func (s *Repository[T, K]) scope(ctx context.Context, conditions []string, values []interface{}) (string, []interface{}, error) {
+	if s.softDelete != nil && !s.unscoped {
+		conditions = append(conditions, s.softDelete.Condition(GetDialect(s.Database)))
+	}
+	if s.tenant != nil {
+		tenant, err := s.tenant.Value(ctx)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, s.tenant.Condition(GetDialect(s.Database), s.BuildParam(len(values)+1)))
+		values = append(values, tenant)
+	}
+	if len(conditions) == 0 {
+		return "", values, nil
+	}
+	return "where " + strings.Join(conditions, " and "), values, nil
+}
+
+func (s *Repository[T, K]) All(ctx context.Context) ([]T, error) {
+	where, values, err := s.scope(ctx, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	query := BuildSelectAllQuery(s.table, GetDialect(s.Database))
+	if len(where) > 0 {
+		query = query + " " + where
+	}
+	var result []T
+	err = Query(ctx, s.Database, &result, query, values...)
+	if err != nil {
+		return result, err
+	}
+	if s.Mapper != nil {
+		_, err = MapModels(ctx, &result, s.Mapper.DbToModel)
+	}
+	return result, err
+}
+
+func (s *Repository[T, K]) Load(ctx context.Context, id K) (*T, error) {
+	where, values, err := s.buildWhere(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("select * from %s %s", QuoteName(GetDialect(s.Database), s.table), where)
+	r, err := QueryRow(ctx, s.Database, s.modelType, s.fieldsIndex, query, values...)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	model := r.(*T)
+	if s.Mapper != nil {
+		_, er2 := s.Mapper.DbToModel(ctx, model)
+		if er2 != nil {
+			return model, er2
+		}
+	}
+	return model, nil
+}
+
+func (s *Repository[T, K]) Exist(ctx context.Context, id K) (bool, error) {
+	where, values, err := s.buildWhere(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	count, err := Count(ctx, s.Database, fmt.Sprintf("select count(*) from %s %s", QuoteName(GetDialect(s.Database), s.table), where), values...)
+	if err != nil {
+		return false, err
+	}
+	return count >= 1, nil
+}
+
+func (s *Repository[T, K]) toDb(ctx context.Context, model *T) (interface{}, error) {
+	if s.Mapper != nil {
+		return s.Mapper.ModelToDb(ctx, model)
+	}
+	return model, nil
+}
+
+func (s *Repository[T, K]) Insert(ctx context.Context, model *T) (int64, error) {
+	s.Audit.OnInsert(ctx, model)
+	m2, err := s.toDb(ctx, model)
+	if err != nil {
+		return 0, err
+	}
+	return Insert(ctx, s.Database, s.table, m2, s.BuildParam)
+}
+
+func (s *Repository[T, K]) Update(ctx context.Context, model *T) (int64, error) {
+	s.Audit.OnUpdate(ctx, model)
+	m2, err := s.toDb(ctx, model)
+	if err != nil {
+		return 0, err
+	}
+	return Update(ctx, s.Database, s.table, m2, s.BuildParam)
+}
+
+func (s *Repository[T, K]) Patch(ctx context.Context, model map[string]interface{}) (int64, error) {
+	s.Audit.OnPatch(ctx, model, s.modelType)
+	if s.Mapper != nil {
+		_, err := s.Mapper.ModelToDb(ctx, &model)
+		if err != nil {
+			return 0, err
+		}
+	}
+	MapToDB(&model, s.modelType)
+	return Patch(ctx, s.Database, s.table, model, s.modelType, s.BuildParam)
+}
+
+func (s *Repository[T, K]) Save(ctx context.Context, model *T) (int64, error) {
+	s.Audit.OnInsert(ctx, model)
+	if err := SetTenant(ctx, model); err != nil {
+		return 0, err
+	}
+	m2, err := s.toDb(ctx, model)
+	if err != nil {
+		return 0, err
+	}
+	return Save(ctx, s.Database, s.table, m2)
+}
+
+// Delete soft deletes the row if the model has a soft-delete column, unless the repository is unscoped.
+func (s *Repository[T, K]) Delete(ctx context.Context, id K) (int64, error) {
+	query, err := s.buildQueryById(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if s.softDelete != nil && !s.unscoped {
+		return MarkDeleted(ctx, s.Database, s.table, query, s.softDelete, s.BuildParam)
+	}
+	return Delete(ctx, s.Database, s.table, query, s.BuildParam)
+}
+
+// HardDelete deletes the row, even if the model has a soft-delete column.
+func (s *Repository[T, K]) HardDelete(ctx context.Context, id K) (int64, error) {
+	query, err := s.buildQueryById(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	return Delete(ctx, s.Database, s.table, query, s.BuildParam)
+}
+
+// Restore restores the soft deleted row.
+func (s *Repository[T, K]) Restore(ctx context.Context, id K) (int64, error) {
+	query, err := s.buildQueryById(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	return Restore(ctx, s.Database, s.table, query, s.softDelete, s.BuildParam)
+}
+
+// buildQueryById builds the query of the row by id, which is scoped by the tenant of ctx if the model has a tenant column.
+func (s *Repository[T, K]) buildQueryById(ctx context.Context, id K) (map[string]interface{}, error) {
+	query := s.BuildKeyMap(id)
+	if s.tenant != nil {
+		tenant, err := s.tenant.Value(ctx)
+		if err != nil {
+			return nil, err
+		}
+		query[s.tenant.Column] = tenant
+	}
+	return query, nil
+}
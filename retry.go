@@ -0,0 +1,128 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// RetryPolicy retries statements and transactions which failed because of a deadlock or a serialization failure.
+type RetryPolicy struct {
+	Backoff   Backoff
+	Retriable func(err error) bool
+}
+
+func NewRetryPolicy(c BackoffConfig) *RetryPolicy {
+	if c.MaxAttempts <= 1 && c.MaxElapsedTime <= 0 {
+		return nil
+	}
+	return &RetryPolicy{Backoff: NewBackoff(c)}
+}
+
+func IsRetriable(err error) bool {
+	return errors.Is(err, ErrDeadlock) || errors.Is(err, ErrSerializationFailure)
+}
+
+// Do calls fn until it succeeds, returns an error which is not retriable, or the backoff stops.
+func (p *RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	retriable := p.Retriable
+	if retriable == nil {
+		retriable = IsRetriable
+	}
+	start := time.Now()
+	var err error
+	for i := 1; ; i++ {
+		err = fn()
+		if err == nil || !retriable(err) {
+			return err
+		}
+		d, ok := p.Backoff.NextDelay(i, time.Since(start))
+		if !ok {
+			return err
+		}
+		if er2 := Sleep(ctx, d); er2 != nil {
+			return err
+		}
+	}
+}
+
+// Sleep waits for d, or returns the error of ctx if ctx is done before.
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var retryPolicies sync.Map
+
+// SetRetryPolicy enables the retry of db, or disables it if p is nil. The policy is removed by Close.
+func SetRetryPolicy(db *sql.DB, p *RetryPolicy) {
+	if p == nil {
+		retryPolicies.Delete(db)
+	} else {
+		retryPolicies.Store(db, p)
+	}
+}
+func GetRetryPolicy(db *sql.DB) *RetryPolicy {
+	if p, ok := retryPolicies.Load(db); ok {
+		return p.(*RetryPolicy)
+	}
+	return nil
+}
+
+// RetryTx calls fn with the retry policy of db, unless ctx holds a transaction of db:
+// a statement of a transaction cannot be retried alone, so the owner of the transaction retries it.
+func RetryTx(ctx context.Context, db *sql.DB, fn func() error) error {
+	if GetTx(ctx, db) != nil {
+		return fn()
+	}
+	target, err := Route(ctx, db)
+	if err != nil {
+		return err
+	}
+	p := GetRetryPolicy(target)
+	if p == nil {
+		return fn()
+	}
+	return p.Do(ctx, fn)
+}
+
+type retryExecutor struct {
+	db     *sql.DB
+	driver string
+	policy *RetryPolicy
+}
+
+func (e *retryExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var res sql.Result
+	err := e.policy.Do(ctx, func() (err error) {
+		res, err = e.db.ExecContext(ctx, query, args...)
+		return ClassifyError(e.driver, err)
+	})
+	return res, err
+}
+func (e *retryExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := e.policy.Do(ctx, func() (err error) {
+		rows, err = e.db.QueryContext(ctx, query, args...)
+		return ClassifyError(e.driver, err)
+	})
+	return rows, err
+}
+
+// QueryRowContext retries the query by the error of the row, which is returned by Scan after the last attempt.
+func (e *retryExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	var row *sql.Row
+	_ = e.policy.Do(ctx, func() error {
+		row = e.db.QueryRowContext(ctx, query, args...)
+		return ClassifyError(e.driver, row.Err())
+	})
+	return row
+}
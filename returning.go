@@ -0,0 +1,180 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// UpdateReturning updates model and fills it with the stored row, including the columns set by defaults or triggers.
+func UpdateReturning(ctx context.Context, db *sql.DB, table string, model interface{}, options ...func(i int) string) (int64, error) {
+	var buildParam func(i int) string
+	if len(options) > 0 && options[0] != nil {
+		buildParam = options[0]
+	} else {
+		buildParam = GetBuild(db)
+	}
+	t, tenant, err := tenantOf(ctx, model)
+	if err != nil {
+		return -1, err
+	}
+	dialect := GetDialect(db)
+	query, values := BuildUpdate(table, model, 0, buildParam, dialect)
+	query, values = t.Where(dialect, query, values, tenant, buildParam)
+	_, keys, _, _ := BuildMapDataAndKeys(model, true)
+	if t != nil {
+		keys[t.Column] = tenant
+	}
+	return ExecReturning(ctx, db, table, query, values, keys, model)
+}
+
+// UpdateWithVersionReturning is UpdateWithVersion, which fills model with the stored row.
+func UpdateWithVersionReturning(ctx context.Context, db *sql.DB, table string, model interface{}, versionIndex int, options ...func(i int) string) (int64, error) {
+	if versionIndex < 0 {
+		return 0, errors.New("version's index not found")
+	}
+	var buildParam func(i int) string
+	if len(options) > 0 && options[0] != nil {
+		buildParam = options[0]
+	} else {
+		buildParam = GetBuild(db)
+	}
+	return updateWithVersion(ctx, db, table, model, versionIndex, getVersionStrategy(model, versionIndex), buildParam, true)
+}
+
+// PatchReturning patches the row and fills result, which is a pointer to a struct of modelType, with the stored row.
+func PatchReturning(ctx context.Context, db *sql.DB, table string, model map[string]interface{}, modelType reflect.Type, result interface{}, options ...func(i int) string) (int64, error) {
+	idColumnNames, idJsonNames := FindPrimaryKeys(modelType)
+	columnNames := FindJsonName(modelType)
+	var buildParam func(i int) string
+	if len(options) > 0 && options[0] != nil {
+		buildParam = options[0]
+	} else {
+		buildParam = GetBuild(db)
+	}
+	t, tenant, err := patchTenant(ctx, model, modelType)
+	if err != nil {
+		return -1, err
+	}
+	dialect := GetDialect(db)
+	query, values := BuildPatch(table, model, columnNames, idJsonNames, idColumnNames, buildParam, dialect)
+	if query == "" {
+		return 0, errors.New("fail to build query")
+	}
+	query, values = t.Where(dialect, query, values, tenant, buildParam)
+	keys := buildKeys(model, idJsonNames, idColumnNames)
+	if t != nil {
+		keys[t.Column] = tenant
+	}
+	return ExecReturning(ctx, db, table, query, values, keys, result)
+}
+
+// PatchWithVersionReturning is PatchWithVersion, which fills result with the stored row.
+func PatchWithVersionReturning(ctx context.Context, db *sql.DB, table string, model map[string]interface{}, modelType reflect.Type, versionIndex int, result interface{}, options ...func(i int) string) (int64, error) {
+	if versionIndex < 0 {
+		return 0, errors.New("version's index not found")
+	}
+	var buildParam func(i int) string
+	if len(options) > 0 && options[0] != nil {
+		buildParam = options[0]
+	} else {
+		buildParam = GetBuild(db)
+	}
+	return patchWithVersion(ctx, db, table, model, modelType, versionIndex, GetVersionStrategy(modelType.Field(versionIndex).Type), buildParam, result)
+}
+
+// ExecReturning executes the update statement and fills result, which is a pointer to a struct, with the updated row.
+// It uses "returning" or "output inserted" if the dialect supports them, or else selects the row by keys, which are the values of the key columns, in the same transaction.
+func ExecReturning(ctx context.Context, db *sql.DB, table string, query string, values []interface{}, keys map[string]interface{}, result interface{}) (int64, error) {
+	fieldsIndex, err := GetColumnIndexes(reflect.Indirect(reflect.ValueOf(result)).Type())
+	if err != nil {
+		return 0, err
+	}
+	dialect := GetDialect(db)
+	switch dialect.GeneratedKey() {
+	case KeyReturning:
+		if r, ok := dialect.(Returner); ok && updateReturning(dialect) {
+			return queryReturning(ctx, db, query+r.Returning("*"), values, result, fieldsIndex)
+		}
+	case KeyOutputInserted:
+		if i := strings.LastIndex(query, " where "); i >= 0 {
+			return queryReturning(ctx, db, query[:i]+" output inserted.*"+query[i:], values, result, fieldsIndex)
+		}
+	}
+	var count int64
+	err = RunInTx(ctx, db, func(ctx context.Context) error {
+		r, er1 := GetExecutor(ctx, db).ExecContext(ctx, query, values...)
+		if er1 != nil {
+			return er1
+		}
+		count, er1 = r.RowsAffected()
+		if er1 != nil || count <= 0 {
+			return er1
+		}
+		selectQuery, selectValues := buildSelectByKeys(dialect, table, "*", keys)
+		rows, er2 := GetExecutor(ctx, db).QueryContext(ctx, selectQuery, selectValues...)
+		if er2 != nil {
+			return er2
+		}
+		_, er2 = scanReturning(rows, result, fieldsIndex)
+		return er2
+	})
+	if err != nil {
+		return -1, HandleError(db, err)
+	}
+	return count, nil
+}
+
+func queryReturning(ctx context.Context, db *sql.DB, query string, values []interface{}, result interface{}, fieldsIndex map[string]int) (int64, error) {
+	rows, err := GetExecutor(ctx, db).QueryContext(ctx, query, values...)
+	if err != nil {
+		return -1, HandleError(db, err)
+	}
+	count, err := scanReturning(rows, result, fieldsIndex)
+	if err != nil {
+		return -1, HandleError(db, err)
+	}
+	return count, nil
+}
+func scanReturning(rows *sql.Rows, result interface{}, fieldsIndex map[string]int) (int64, error) {
+	defer rows.Close()
+	columns, err := GetColumns(rows.Columns())
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	for rows.Next() {
+		r, swapValues := StructScan(result, columns, fieldsIndex, -1)
+		if err = rows.Scan(r...); err != nil {
+			return count, err
+		}
+		SwapValuesToBool(result, &swapValues)
+		count++
+	}
+	return count, rows.Err()
+}
+func buildKeys(model map[string]interface{}, idJsonNames []string, idColumnNames []string) map[string]interface{} {
+	keys := make(map[string]interface{})
+	for i, key := range idJsonNames {
+		keys[idColumnNames[i]] = model[key]
+	}
+	return keys
+}
+func buildSelectByKeys(d Dialect, table string, selectColumns string, keys map[string]interface{}) (string, []interface{}) {
+	columns := make([]string, 0, len(keys))
+	for column := range keys {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	conditions := make([]string, 0, len(columns))
+	values := make([]interface{}, 0, len(columns))
+	for i, column := range columns {
+		conditions = append(conditions, fmt.Sprintf("%s = %s", QuoteName(d, column), d.BuildParam(i+1)))
+		values = append(values, keys[column])
+	}
+	return fmt.Sprintf("select %s from %s where %s", selectColumns, QuoteName(d, table), strings.Join(conditions, " and ")), values
+}
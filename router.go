@@ -0,0 +1,181 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+)
+
+// Router routes the statements of a shared database to the database of the tenant of the context, for the tenants which require physical isolation.
+// The Writer, Loader, Searcher and ActionLogWriter of the shared database run their statements on the database of the tenant,
+// because GetExecutor and BeginTx use the database returned by Route.
+// The database of a tenant is opened by OpenContext when it is used for the first time, with the pool limits of its config.
+type Router struct {
+	DB *sql.DB
+	// Config returns the config of the database of the tenant, or nil if the tenant uses the shared database.
+	Config  func(ctx context.Context, tenant interface{}) (*Config, error)
+	mu      sync.Mutex
+	tenants map[interface{}]*tenantDB
+}
+type tenantDB struct {
+	ready chan struct{}
+	db    *sql.DB
+	err   error
+}
+
+var routers sync.Map
+
+// NewRouter creates the router of the shared database db, and registers it, so that db is routed by the tenant of the context.
+func NewRouter(db *sql.DB, config func(ctx context.Context, tenant interface{}) (*Config, error)) *Router {
+	r := &Router{DB: db, Config: config, tenants: make(map[interface{}]*tenantDB)}
+	routers.Store(db, r)
+	return r
+}
+
+// NewSchemaRouter routes each tenant to its schema, which is returned by schema, in a database of config c.
+// An empty schema means the shared database.
+func NewSchemaRouter(db *sql.DB, c Config, schema func(ctx context.Context, tenant interface{}) (string, error)) *Router {
+	return NewRouter(db, func(ctx context.Context, tenant interface{}) (*Config, error) {
+		name, err := schema(ctx, tenant)
+		if err != nil || len(name) == 0 {
+			return nil, err
+		}
+		c2 := c
+		c2.Schema = name
+		return &c2, nil
+	})
+}
+
+func GetRouter(db *sql.DB) *Router {
+	if r, ok := routers.Load(db); ok {
+		return r.(*Router)
+	}
+	return nil
+}
+
+// Route returns the database of the tenant of ctx, or the shared database if there is no tenant or the tenant has no database of its own.
+func (r *Router) Route(ctx context.Context) (*sql.DB, error) {
+	tenant := GetTenant(ctx)
+	if tenant == nil {
+		return r.DB, nil
+	}
+	return r.Get(ctx, tenant)
+}
+
+// Get returns the database of tenant, which is opened if it is not opened yet. A failed open is tried again by the next call.
+func (r *Router) Get(ctx context.Context, tenant interface{}) (*sql.DB, error) {
+	r.mu.Lock()
+	t, ok := r.tenants[tenant]
+	if !ok {
+		t = &tenantDB{ready: make(chan struct{})}
+		r.tenants[tenant] = t
+	}
+	r.mu.Unlock()
+	if ok {
+		select {
+		case <-t.ready:
+			return t.db, t.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	t.db, t.err = r.open(ctx, tenant)
+	if t.err != nil {
+		r.mu.Lock()
+		delete(r.tenants, tenant)
+		r.mu.Unlock()
+	}
+	close(t.ready)
+	return t.db, t.err
+}
+func (r *Router) open(ctx context.Context, tenant interface{}) (*sql.DB, error) {
+	c, err := r.Config(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return r.DB, nil
+	}
+	db, err := OpenContext(ctx, *c)
+	if err == nil && db == nil {
+		// a mock config
+		return r.DB, nil
+	}
+	return db, err
+}
+
+// Close closes the databases of the tenants, but not the shared database, and unregisters the router.
+func (r *Router) Close() error {
+	routers.Delete(r.DB)
+	r.mu.Lock()
+	tenants := r.tenants
+	r.tenants = make(map[interface{}]*tenantDB)
+	r.mu.Unlock()
+	closed := make(map[*sql.DB]bool)
+	var err error
+	for _, t := range tenants {
+		<-t.ready
+		if t.db == nil || t.db == r.DB || closed[t.db] {
+			continue
+		}
+		closed[t.db] = true
+		if er1 := Close(t.db); er1 != nil && err == nil {
+			err = er1
+		}
+	}
+	return err
+}
+
+// Route returns the database of the tenant of ctx if db has a router, or else db itself.
+func Route(ctx context.Context, db *sql.DB) (*sql.DB, error) {
+	if db == nil {
+		return db, nil
+	}
+	r := GetRouter(db)
+	if r == nil {
+		return db, nil
+	}
+	return r.Route(ctx)
+}
+
+// routeErrorKey holds the error of Route in the context of the statements of failedDB, which cannot return an error from GetExecutor.
+type routeErrorKey struct{}
+type routeErrorConnector struct{}
+
+func (c routeErrorConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err, ok := ctx.Value(routeErrorKey{}).(error); ok {
+		return nil, err
+	}
+	return nil, ErrConnection
+}
+func (c routeErrorConnector) Driver() driver.Driver {
+	return nil
+}
+
+var (
+	failedDBOnce sync.Once
+	failedDB     *sql.DB
+)
+
+// routeError returns an executor which fails with err, because the database of the tenant cannot be opened.
+func routeError(err error) Executor {
+	failedDBOnce.Do(func() {
+		failedDB = sql.OpenDB(routeErrorConnector{})
+	})
+	return &failedExecutor{err: err}
+}
+
+type failedExecutor struct {
+	err error
+}
+
+func (e *failedExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, e.err
+}
+func (e *failedExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, e.err
+}
+func (e *failedExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return failedDB.QueryRowContext(context.WithValue(ctx, routeErrorKey{}, e.err), query, args...)
+}
@@ -3,14 +3,21 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"reflect"
 )
 
 type SearchBuilder struct {
 	Database   *sql.DB
 	BuildQuery func(sm interface{}) (string, []interface{})
-	ModelType  reflect.Type
-	Map        func(ctx context.Context, model interface{}) (interface{}, error)
+	// BuildQueryContext is used instead of BuildQuery if it is set, to scope the query by the context, such as the tenant
+	BuildQueryContext func(ctx context.Context, sm interface{}) (string, []interface{})
+	// BuildQueryWithError is used instead of BuildQueryContext and BuildQuery if it is set, and its error is returned by Search, such as query.BuildWithError
+	BuildQueryWithError func(ctx context.Context, sm interface{}) (string, []interface{}, error)
+	ModelType         reflect.Type
+	Map               func(ctx context.Context, model interface{}) (interface{}, error)
+	// Paging counts the rows of Search, such as PagingWindow; see BuildFromQueryWithPaging
+	Paging string
 }
 
 func NewSearchBuilder(db *sql.DB, modelType reflect.Type, buildQuery func(interface{}) (string, []interface{}), options ...func(context.Context, interface{}) (interface{}, error)) *SearchBuilder {
@@ -21,14 +28,72 @@ func NewSearchBuilder(db *sql.DB, modelType reflect.Type, buildQuery func(interf
 	builder := &SearchBuilder{Database: db, BuildQuery: buildQuery, ModelType: modelType, Map: mp}
 	return builder
 }
+func NewSearchBuilderWithContext(db *sql.DB, modelType reflect.Type, buildQuery func(context.Context, interface{}) (string, []interface{}), options ...func(context.Context, interface{}) (interface{}, error)) *SearchBuilder {
+	var mp func(context.Context, interface{}) (interface{}, error)
+	if len(options) >= 1 {
+		mp = options[0]
+	}
+	return &SearchBuilder{Database: db, BuildQueryContext: buildQuery, ModelType: modelType, Map: mp}
+}
+func NewSearchBuilderWithError(db *sql.DB, modelType reflect.Type, buildQuery func(context.Context, interface{}) (string, []interface{}, error), options ...func(context.Context, interface{}) (interface{}, error)) *SearchBuilder {
+	var mp func(context.Context, interface{}) (interface{}, error)
+	if len(options) >= 1 {
+		mp = options[0]
+	}
+	return &SearchBuilder{Database: db, BuildQueryWithError: buildQuery, ModelType: modelType, Map: mp}
+}
 
+// Search loads the page of pageIndex, or the page after the cursor if m has a cursor, which is not nil; see Cursor.
+// It returns ErrTenantRequired if the model has a tenant column and ctx has no tenant.
+// The query of a search model with a cursor must have the keyset condition and the order by clause of the cursor, as built by query.Build.
 func (b *SearchBuilder) Search(ctx context.Context, m interface{}, results interface{}, pageIndex int64, pageSize int64, options...int64) (int64, error) {
-	sql, params := b.BuildQuery(m)
+	if FindTenant(b.ModelType) != nil && GetTenant(ctx) == nil {
+		return -1, ErrTenantRequired
+	}
+	cursor, sort := FindCursor(m)
+	var keys []SortKey
+	if cursor != nil {
+		keys = BuildSortKeys(sort, b.ModelType)
+		if len(*cursor) > 0 {
+			if _, err := DecodeCursor(*cursor, keys, b.ModelType); err != nil {
+				return -1, err
+			}
+		}
+	}
+	sql, params, err := b.buildQuery(ctx, m)
+	if err != nil {
+		return -1, err
+	}
+	if cursor != nil {
+		return BuildFromCursor(ctx, b.Database, results, sql, params, pageSize, cursor, keys, b.Map)
+	}
 	var firstPageSize int64
 	if len(options) > 0 && options[0] > 0 {
 		firstPageSize = options[0]
 	} else {
 		firstPageSize = 0
 	}
-	return BuildFromQuery(ctx, b.Database, results, sql, params, pageIndex, pageSize, firstPageSize, b.Map)
+	return BuildFromQueryWithPaging(ctx, b.Database, results, sql, params, pageIndex, pageSize, firstPageSize, b.Paging, b.Map)
+}
+
+// buildQuery builds the query of m. The panic of query.Build for an invalid search model is returned as an error, wrapping ErrInvalidFilter or ErrInvalidCursor.
+func (b *SearchBuilder) buildQuery(ctx context.Context, m interface{}) (query string, params []interface{}, err error) {
+	if b.BuildQueryWithError != nil {
+		return b.BuildQueryWithError(ctx, m)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(error)
+			if !ok || !(errors.Is(e, ErrInvalidFilter) || errors.Is(e, ErrInvalidCursor)) {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+	if b.BuildQueryContext != nil {
+		query, params = b.BuildQueryContext(ctx, m)
+	} else {
+		query, params = b.BuildQuery(m)
+	}
+	return query, params, nil
 }
@@ -22,3 +22,20 @@ func NewSearcherWithQuery(db *sql.DB, modelType reflect.Type, buildQuery func(in
 	builder := NewSearchBuilder(db, modelType, buildQuery, options...)
 	return NewSearcher(builder.Search)
 }
+func NewSearcherWithContext(db *sql.DB, modelType reflect.Type, buildQuery func(context.Context, interface{}) (string, []interface{}), options ...func(context.Context, interface{}) (interface{}, error)) *Searcher {
+	builder := NewSearchBuilderWithContext(db, modelType, buildQuery, options...)
+	return NewSearcher(builder.Search)
+}
+
+// NewSearcherWithError creates a searcher, of which Search returns the error of buildQuery, such as query.BuildWithError.
+func NewSearcherWithError(db *sql.DB, modelType reflect.Type, buildQuery func(context.Context, interface{}) (string, []interface{}, error), options ...func(context.Context, interface{}) (interface{}, error)) *Searcher {
+	builder := NewSearchBuilderWithError(db, modelType, buildQuery, options...)
+	return NewSearcher(builder.Search)
+}
+
+// NewSearcherWithPaging creates a searcher, which counts the rows by paging, such as PagingWindow.
+func NewSearcherWithPaging(db *sql.DB, modelType reflect.Type, buildQuery func(interface{}) (string, []interface{}), paging string, options ...func(context.Context, interface{}) (interface{}, error)) *Searcher {
+	builder := NewSearchBuilder(db, modelType, buildQuery, options...)
+	builder.Paging = paging
+	return NewSearcher(builder.Search)
+}
@@ -11,6 +11,7 @@ type SizeBatchInserter struct {
 	tableName  string
 	BuildParam func(i int) string
 	Map        func(ctx context.Context, model interface{}) (interface{}, error)
+	Audit      *Audit
 }
 func NewSizeBatchInserter(db *sql.DB, tableName string, options...func(context.Context, interface{}) (interface{}, error)) *SizeBatchInserter {
 	var mp func(context.Context, interface{}) (interface{}, error)
@@ -26,10 +27,11 @@ func NewSizeSqlBatchInserter(db *sql.DB, tableName string, mp func(context.Conte
 	} else {
 		buildParam = GetBuild(db)
 	}
-	return &SizeBatchInserter{db: db, tableName: tableName, BuildParam: buildParam, Map: mp}
+	return &SizeBatchInserter{db: db, tableName: tableName, BuildParam: buildParam, Map: mp, Audit: NewAudit(GetAuditUser(db))}
 }
 
 func (w *SizeBatchInserter) Write(ctx context.Context, models interface{}) ([]int, []int, error) {
+	w.Audit.OnInsert(ctx, models)
 	successIndices := make([]int, 0)
 	failIndices := make([]int, 0)
 	var models2 interface{}
@@ -46,6 +48,10 @@ func (w *SizeBatchInserter) Write(ctx context.Context, models interface{}) ([]in
 		models2 = models
 	}
 	s := reflect.ValueOf(models2)
+	if er0 = SetTenant(ctx, models2); er0 != nil {
+		failIndices = ToArrayIndex(s, failIndices)
+		return successIndices, failIndices, er0
+	}
 	_models, er1 := InterfaceSlice(models2)
 	if er1 != nil {
 		// Return full fail
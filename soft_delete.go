@@ -0,0 +1,223 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SoftDeleteTag = "softdelete"
+	ActiveTag     = "active"
+)
+
+// SoftDelete is the soft-delete column of a model, which is tagged by softDelete in the gorm tag:
+//   - a time field is set to the current time, and the rows with null are not deleted: `gorm:"column:deleted_at;softDelete"`
+//   - a bool field is set to true: `gorm:"column:deleted;softDelete"`
+//   - other fields are set to the value of the tag, and restored to the value of the active tag, or else to null: `gorm:"column:status;softDelete:D;active:A"`
+type SoftDelete struct {
+	Index   int
+	Column  string
+	Type    reflect.Type
+	Deleted string
+	Active  *string
+}
+
+var softDeleteCache sync.Map
+
+// FindSoftDelete returns the soft-delete column of modelType, or nil if there is no field tagged by softDelete.
+func FindSoftDelete(modelType reflect.Type) *SoftDelete {
+	if modelType == nil {
+		return nil
+	}
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	if modelType.Kind() != reflect.Struct {
+		return nil
+	}
+	if v, ok := softDeleteCache.Load(modelType); ok {
+		return v.(*SoftDelete)
+	}
+	sd := findSoftDelete(modelType)
+	softDeleteCache.Store(modelType, sd)
+	return sd
+}
+func findSoftDelete(modelType reflect.Type) *SoftDelete {
+	numField := modelType.NumField()
+	for i := 0; i < numField; i++ {
+		field := modelType.Field(i)
+		tag, ok := field.Tag.Lookup("gorm")
+		if !ok {
+			continue
+		}
+		var sd *SoftDelete
+		var active *string
+		for _, s := range strings.Split(tag, ";") {
+			kv := strings.SplitN(strings.TrimSpace(s), ":", 2)
+			switch strings.ToLower(kv[0]) {
+			case SoftDeleteTag:
+				column, exist := GetColumnNameByIndex(modelType, i)
+				if !exist {
+					panic(fmt.Sprintf("column of soft delete field %s not found", field.Name))
+				}
+				sd = &SoftDelete{Index: i, Column: column, Type: field.Type}
+				if len(kv) > 1 {
+					sd.Deleted = kv[1]
+				}
+			case ActiveTag:
+				if len(kv) > 1 {
+					v := kv[1]
+					active = &v
+				}
+			}
+		}
+		if sd != nil {
+			sd.Active = active
+			if !sd.isTime() && !sd.isBool() && len(sd.Deleted) == 0 {
+				panic(fmt.Sprintf("value of soft delete field %s not found", field.Name))
+			}
+			return sd
+		}
+	}
+	return nil
+}
+
+func (s *SoftDelete) isTime() bool {
+	t := s.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == reflect.TypeOf(time.Time{})
+}
+func (s *SoftDelete) isBool() bool {
+	t := s.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Bool
+}
+
+// Condition returns the condition of the rows which are not deleted. The column is qualified by the table in options, if any.
+// If d is nil, the condition is of DefaultDialect.
+func (s *SoftDelete) Condition(d Dialect, options ...string) string {
+	if d == nil {
+		d = DefaultDialect{}
+	}
+	column := s.Column
+	if len(options) > 0 && len(options[0]) > 0 {
+		column = options[0] + "." + column
+	}
+	column = QuoteName(d, column)
+	if s.isTime() {
+		return column + " is null"
+	}
+	if s.isBool() {
+		return fmt.Sprintf("(%s is null or %s = %s)", column, column, d.Bool(false))
+	}
+	return fmt.Sprintf("(%s is null or %s <> %s)", column, column, quoteLiteral(s.Deleted))
+}
+
+// deleted returns the value of the deleted rows, which is nil if it is a literal in set.
+func (s *SoftDelete) deleted(d Dialect) (set string, value interface{}) {
+	if s.isTime() {
+		return "", time.Now()
+	}
+	if s.isBool() {
+		return d.Bool(true), nil
+	}
+	return "", s.Deleted
+}
+func (s *SoftDelete) active(d Dialect) (set string, value interface{}) {
+	if s.isBool() {
+		return d.Bool(false), nil
+	}
+	if !s.isTime() && s.Active != nil {
+		return "", *s.Active
+	}
+	return "null", nil
+}
+func quoteLiteral(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// BuildSoftDelete builds the statement to mark the rows of query as deleted, if they are not deleted yet. The dialect is DefaultDialect if there is no dialect in options.
+func BuildSoftDelete(table string, query map[string]interface{}, softDelete *SoftDelete, buildParam func(int) string, options ...Dialect) (string, []interface{}) {
+	d := getSoftDeleteDialect(options)
+	set, value := softDelete.deleted(d)
+	return buildSoftDeleteUpdate(d, table, query, softDelete, set, value, buildParam, softDelete.Condition(d))
+}
+
+// BuildRestore builds the statement to restore the deleted rows of query. The dialect is DefaultDialect if there is no dialect in options.
+func BuildRestore(table string, query map[string]interface{}, softDelete *SoftDelete, buildParam func(int) string, options ...Dialect) (string, []interface{}) {
+	d := getSoftDeleteDialect(options)
+	set, value := softDelete.active(d)
+	return buildSoftDeleteUpdate(d, table, query, softDelete, set, value, buildParam, "not "+softDelete.Condition(d))
+}
+
+// getSoftDeleteDialect returns the dialect of options, or DefaultDialect, because the value of a bool column depends on the dialect.
+func getSoftDeleteDialect(options []Dialect) Dialect {
+	if d := getDialect(options); d != nil {
+		return d
+	}
+	return DefaultDialect{}
+}
+func buildSoftDeleteUpdate(d Dialect, table string, query map[string]interface{}, softDelete *SoftDelete, set string, value interface{}, buildParam func(int) string, condition string) (string, []interface{}) {
+	var values []interface{}
+	i := 1
+	if len(set) == 0 {
+		set = buildParam(i)
+		values = append(values, value)
+		i++
+	}
+	conditions := make([]string, 0)
+	for key, v := range query {
+		conditions = append(conditions, fmt.Sprintf("%s = %s", QuoteName(d, key), buildParam(i)))
+		values = append(values, v)
+		i++
+	}
+	conditions = append(conditions, condition)
+	return fmt.Sprintf("update %s set %s = %s where %s", QuoteName(d, table), QuoteName(d, softDelete.Column), set, strings.Join(conditions, " and ")), values
+}
+
+// MarkDeleted soft deletes the rows of query.
+func MarkDeleted(ctx context.Context, db *sql.DB, table string, query map[string]interface{}, softDelete *SoftDelete, options ...func(i int) string) (int64, error) {
+	if softDelete == nil {
+		return 0, errors.New("soft delete column not found")
+	}
+	var buildParam func(i int) string
+	if len(options) > 0 && options[0] != nil {
+		buildParam = options[0]
+	} else {
+		buildParam = GetBuild(db)
+	}
+	stmt, values := BuildSoftDelete(table, query, softDelete, buildParam, GetDialect(db))
+	return execSoftDelete(ctx, db, stmt, values)
+}
+
+// Restore restores the soft deleted rows of query.
+func Restore(ctx context.Context, db *sql.DB, table string, query map[string]interface{}, softDelete *SoftDelete, options ...func(i int) string) (int64, error) {
+	if softDelete == nil {
+		return 0, errors.New("soft delete column not found")
+	}
+	var buildParam func(i int) string
+	if len(options) > 0 && options[0] != nil {
+		buildParam = options[0]
+	} else {
+		buildParam = GetBuild(db)
+	}
+	stmt, values := BuildRestore(table, query, softDelete, buildParam, GetDialect(db))
+	return execSoftDelete(ctx, db, stmt, values)
+}
+func execSoftDelete(ctx context.Context, db *sql.DB, stmt string, values []interface{}) (int64, error) {
+	result, err := GetExecutor(ctx, db).ExecContext(ctx, stmt, values...)
+	if err != nil {
+		return -1, HandleError(db, err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,83 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type softDeletedItem struct {
+	Id        string     `json:"id" gorm:"column:id;primary_key"`
+	DeletedAt *time.Time `json:"deletedAt" gorm:"column:deleted_at;softDelete"`
+}
+
+func TestFindSoftDelete(t *testing.T) {
+	modelType := reflect.TypeOf(softDeletedItem{})
+	sd := FindSoftDelete(modelType)
+	if sd == nil || sd.Index != 1 || sd.Column != "deleted_at" || !sd.isTime() {
+		t.Fatalf("FindSoftDelete() = %+v, want the time column deleted_at", sd)
+	}
+	if sd2 := FindSoftDelete(reflect.PtrTo(modelType)); sd2 != sd {
+		t.Error("FindSoftDelete() of the pointer type is not the cached soft-delete column")
+	}
+	if sd3 := FindSoftDelete(reflect.TypeOf(versionedItem{})); sd3 != nil {
+		t.Errorf("FindSoftDelete() = %+v, want nil", sd3)
+	}
+}
+
+type softDeletedFlag struct {
+	Id      string `json:"id" gorm:"column:id;primary_key"`
+	Deleted bool   `json:"deleted" gorm:"column:deleted;softDelete"`
+}
+
+func TestSoftDeleteWithoutDialect(t *testing.T) {
+	sd := FindSoftDelete(reflect.TypeOf(softDeletedFlag{}))
+	query := map[string]interface{}{"id": "1"}
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"Condition", sd.Condition(nil), `("deleted" is null or "deleted" = 0)`},
+		{"BuildSoftDelete", first(BuildSoftDelete("items", query, sd, BuildDollarParam)), `update "items" set "deleted" = 1 where "id" = $1 and ("deleted" is null or "deleted" = 0)`},
+		{"BuildRestore", first(BuildRestore("items", query, sd, BuildDollarParam)), `update "items" set "deleted" = 0 where "id" = $1 and not ("deleted" is null or "deleted" = 0)`},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s() = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+func first(query string, values []interface{}) string {
+	return query
+}
+
+func TestRepositorySoftDelete(t *testing.T) {
+	var queries []string
+	db := sql.OpenDB(recordConnector{queries: &queries})
+	SetDialect(db, PostgresDialect{})
+	defer db.Close()
+	r := NewRepository[softDeletedItem, string](db, "items")
+	ctx := context.Background()
+	if where, _, _ := r.scope(ctx, nil, nil); where != `where "deleted_at" is null` {
+		t.Errorf("scope() = %q, want the rows which are not soft deleted", where)
+	}
+	if where, _, _ := r.Unscoped().scope(ctx, nil, nil); where != "" {
+		t.Errorf("Unscoped().scope() = %q, want all rows", where)
+	}
+	r.Delete(ctx, "1")
+	r.Restore(ctx, "1")
+	r.HardDelete(ctx, "1")
+	r.Unscoped().Delete(ctx, "1")
+	want := []string{
+		`update "items" set "deleted_at" = $1 where "id" = $2 and "deleted_at" is null`,
+		`update "items" set "deleted_at" = null where "id" = $1 and not "deleted_at" is null`,
+		`delete from "items" where "id" = $1`,
+		`delete from "items" where "id" = $1`,
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("Delete(), Restore(), HardDelete(), Unscoped().Delete() = %q, want %q", queries, want)
+	}
+}
@@ -9,28 +9,11 @@ import (
 )
 
 func GetDriver(db *sql.DB) string {
-	if db == nil {
-		return DriverNotSupport
-	}
-	driver := reflect.TypeOf(db.Driver()).String()
-	switch driver {
-	case "*pq.Driver":
-		return DriverPostgres
-	case "*godror.drv":
-		return DriverOracle
-	case "*mysql.MySQLDriver":
-		return DriverMysql
-	case "*mssql.Driver":
-		return DriverMssql
-	case "*sqlite3.SQLiteDriver":
-		return DriverSqlite3
-	default:
-		return DriverNotSupport
-	}
+	return GetDialect(db).Name()
 }
 func Count(ctx context.Context, db *sql.DB, sql string, values ...interface{}) (int64, error) {
 	var total int64
-	row := db.QueryRowContext(ctx, sql, values...)
+	row := GetExecutor(ctx, db).QueryRowContext(ctx, sql, values...)
 	err2 := row.Scan(&total)
 	if err2 != nil {
 		return total, err2
@@ -38,7 +21,7 @@ func Count(ctx context.Context, db *sql.DB, sql string, values ...interface{}) (
 	return total, nil
 }
 func Query(ctx context.Context, db *sql.DB, results interface{}, sql string, values ...interface{}) error {
-	rows, er1 := db.QueryContext(ctx, sql, values...)
+	rows, er1 := GetExecutor(ctx, db).QueryContext(ctx, sql, values...)
 	if er1 != nil {
 		return er1
 	}
@@ -128,7 +111,7 @@ func QueryByStatement(ctx context.Context, stm *sql.Stmt, results interface{}, v
 	return nil
 }
 func QueryAndCount(ctx context.Context, db *sql.DB, results interface{}, count *int64, sql string, values ...interface{}) error {
-	rows, er1 := db.QueryContext(ctx, sql, values...)
+	rows, er1 := GetExecutor(ctx, db).QueryContext(ctx, sql, values...)
 	if er1 != nil {
 		return er1
 	}
@@ -165,7 +148,7 @@ func QueryRow(ctx context.Context, db *sql.DB, modelType reflect.Type, fieldsInd
 		strSQL = "AND ROWNUM = 1"
 	}
 	s := sql + " " + strSQL
-	rows, er1 := db.QueryContext(ctx, s, values...)
+	rows, er1 := GetExecutor(ctx, db).QueryContext(ctx, s, values...)
 	if er1 != nil {
 		return nil, er1
 	}
@@ -10,6 +10,7 @@ type SqlWriter struct {
 	tableName  string
 	BuildParam func(i int) string
 	Map        func(ctx context.Context, model interface{}) (interface{}, error)
+	Audit      *Audit
 }
 
 func NewSqlWriterWithMap(db *sql.DB, tableName string, mp func(context.Context, interface{}) (interface{}, error), options ...func(i int) string) *SqlWriter {
@@ -19,7 +20,7 @@ func NewSqlWriterWithMap(db *sql.DB, tableName string, mp func(context.Context,
 	} else {
 		buildParam = GetBuild(db)
 	}
-	return &SqlWriter{db: db, tableName: tableName, BuildParam: buildParam, Map: mp}
+	return &SqlWriter{db: db, tableName: tableName, BuildParam: buildParam, Map: mp, Audit: NewAudit(GetAuditUser(db))}
 }
 
 func NewSqlWriter(db *sql.DB, tableName string, options ...func(ctx context.Context, model interface{}) (interface{}, error)) *SqlWriter {
@@ -31,6 +32,10 @@ func NewSqlWriter(db *sql.DB, tableName string, options ...func(ctx context.Cont
 }
 
 func (w *SqlWriter) Write(ctx context.Context, model interface{}) error {
+	w.Audit.OnInsert(ctx, model)
+	if err := SetTenant(ctx, model); err != nil {
+		return err
+	}
 	if w.Map != nil {
 		m2, er0 := w.Map(ctx, model)
 		if er0 != nil {
@@ -42,7 +42,7 @@ func (s *StringService) Load(ctx context.Context, key string, max int64) ([]stri
 	key = key + "%"
 	vs := make([]string, 0)
 	sql := fmt.Sprintf(s.Sql, max)
-	rows, er1 := s.DB.QueryContext(ctx, sql, key)
+	rows, er1 := GetExecutor(ctx, s.DB).QueryContext(ctx, sql, key)
 	if er1 != nil {
 		return vs, er1
 	}
@@ -62,24 +62,25 @@ func (s *StringService) Load(ctx context.Context, key string, max int64) ([]stri
 func (s *StringService) Save(ctx context.Context, values []string) (int64, error) {
 	mainScope := BatchStatement{}
 	driver := s.Driver
+	upsert := GetDialect(s.DB).Upsert()
 	for _, e := range values {
 		mainScope.Values = append(mainScope.Values, e)
 	}
 	query := ""
 	holders := BuildPlaceHolders(len(mainScope.Values), s.BuildParam)
-	if driver == DriverPostgres {
+	if upsert == UpsertOnConflict || upsert == UpsertStatement {
 		query = fmt.Sprintf("insert into %s (%s) values %s on conflict do nothing",
 			s.Table,
 			s.Field,
 			holders,
 		)
-	} else if driver == DriverSqlite3 {
+	} else if upsert == UpsertInsertOrReplace {
 		query = fmt.Sprintf("insert or ignore into %s (%s) values %s",
 			s.Table,
 			s.Field,
 			holders,
 		)
-	} else if driver == DriverMysql {
+	} else if upsert == UpsertOnDuplicateKey {
 		qKey := s.Field + " = " + s.Field
 		query = fmt.Sprintf("insert into %s (%s) values %s on duplicate key update %s",
 			s.Table,
@@ -87,7 +88,7 @@ func (s *StringService) Save(ctx context.Context, values []string) (int64, error
 			holders,
 			qKey,
 		)
-	} else if driver == DriverOracle || driver == DriverMssql {
+	} else if upsert == UpsertMergeDual || upsert == UpsertMerge {
 		onDupe := s.Table + "." + s.Field + " = " + "temp." + s.Field
 		value := "temp." + s.Field
 		query = fmt.Sprintf("merge into %s using (values %s) as temp (%s) on %s when not matched then insert (%s) values (%s);",
@@ -102,7 +103,7 @@ func (s *StringService) Save(ctx context.Context, values []string) (int64, error
 		return 0, fmt.Errorf("unsupported db vendor, current vendor is %s", driver)
 	}
 	mainScope.Query = query
-	x, err := s.DB.ExecContext(ctx, mainScope.Query, mainScope.Values...)
+	x, err := GetExecutor(ctx, s.DB).ExecContext(ctx, mainScope.Query, mainScope.Values...)
 	if err != nil {
 		return 0, err
 	}
@@ -117,7 +118,7 @@ func (s *StringService) Delete(ctx context.Context, values []string) (int64, err
 		arrValue = append(arrValue, param)
 	}
 	query := `delete from ` + s.Table + ` where ` + s.Field + ` in (` + strings.Join(arrValue, ",") + `)`
-	x, err := s.DB.ExecContext(ctx, query)
+	x, err := GetExecutor(ctx, s.DB).ExecContext(ctx, query)
 	if err != nil {
 		return 0, err
 	}
@@ -0,0 +1,225 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const TenantTag = "tenant"
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx with the tenant, which scopes all reads and writes of the models with a tenant column.
+func WithTenant(ctx context.Context, tenant interface{}) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// GetTenant returns the tenant of ctx, or nil if there is no tenant.
+func GetTenant(ctx context.Context) interface{} {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Value(tenantKey{})
+}
+
+// Tenant is the tenant column of a model, which is tagged by tenant in the gorm tag: `gorm:"column:tenant_id;tenant"`.
+// The rows of a model with a tenant column are read and written only with the tenant of the context; ErrTenantRequired is returned if there is no tenant.
+type Tenant struct {
+	Index  int
+	Column string
+	Json   string
+}
+
+var tenantCache sync.Map
+
+// FindTenant returns the tenant column of modelType, or nil if there is no field tagged by tenant.
+func FindTenant(modelType reflect.Type) *Tenant {
+	if modelType == nil {
+		return nil
+	}
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	if modelType.Kind() != reflect.Struct {
+		return nil
+	}
+	if v, ok := tenantCache.Load(modelType); ok {
+		return v.(*Tenant)
+	}
+	var tenant *Tenant
+	numField := modelType.NumField()
+	for i := 0; i < numField && tenant == nil; i++ {
+		field := modelType.Field(i)
+		tag, ok := field.Tag.Lookup("gorm")
+		if !ok {
+			continue
+		}
+		for _, s := range strings.Split(tag, ";") {
+			if strings.ToLower(strings.TrimSpace(s)) != TenantTag {
+				continue
+			}
+			column, exist := GetColumnNameByIndex(modelType, i)
+			if !exist {
+				panic(fmt.Sprintf("column of tenant field %s not found", field.Name))
+			}
+			json, ok := GetJsonNameByIndex(modelType, i)
+			if !ok || len(json) == 0 {
+				json = field.Name
+			}
+			tenant = &Tenant{Index: i, Column: column, Json: json}
+			break
+		}
+	}
+	tenantCache.Store(modelType, tenant)
+	return tenant
+}
+
+// Condition returns the condition of the rows of the tenant, which is the parameter param. The column is qualified by the table in options, if any.
+func (t *Tenant) Condition(d Dialect, param string, options ...string) string {
+	column := t.Column
+	if len(options) > 0 && len(options[0]) > 0 {
+		column = options[0] + "." + column
+	}
+	return QuoteName(d, column) + " = " + param
+}
+
+// Value returns the tenant of ctx, or ErrTenantRequired if there is no tenant.
+func (t *Tenant) Value(ctx context.Context) (interface{}, error) {
+	tenant := GetTenant(ctx)
+	if tenant == nil {
+		return nil, ErrTenantRequired
+	}
+	return tenant, nil
+}
+
+// Where appends the tenant condition to query, which ends with its where clause, and the tenant to values.
+func (t *Tenant) Where(d Dialect, query string, values []interface{}, tenant interface{}, buildParam func(int) string) (string, []interface{}) {
+	if t == nil {
+		return query, values
+	}
+	return query + " and " + t.Condition(d, buildParam(len(values)+1)), append(values, tenant)
+}
+
+// getTenant returns the tenant column of modelType and the tenant of ctx, or nil if modelType has no tenant column.
+func getTenant(ctx context.Context, modelType reflect.Type) (*Tenant, interface{}, error) {
+	t := FindTenant(modelType)
+	if t == nil {
+		return nil, nil, nil
+	}
+	tenant, err := t.Value(ctx)
+	return t, tenant, err
+}
+
+// tenantOf sets the tenant of model, and returns its tenant column and the tenant of ctx.
+func tenantOf(ctx context.Context, model interface{}) (*Tenant, interface{}, error) {
+	t, tenant, err := getTenant(ctx, reflect.TypeOf(model))
+	if t == nil || err != nil {
+		return t, tenant, err
+	}
+	return t, tenant, SetTenant(ctx, model)
+}
+
+// patchTenant removes the tenant of model, of which the keys are the json names of modelType, so that the tenant of a row is never changed.
+func patchTenant(ctx context.Context, model map[string]interface{}, modelType reflect.Type) (*Tenant, interface{}, error) {
+	t, tenant, err := getTenant(ctx, modelType)
+	if t != nil {
+		delete(model, t.Json)
+	}
+	return t, tenant, err
+}
+
+// SetTenant sets the tenant field of model, which is a pointer to a struct or a slice of them, to the tenant of ctx.
+func SetTenant(ctx context.Context, model interface{}) error {
+	return setTenant(ctx, reflect.ValueOf(model))
+}
+func setTenant(ctx context.Context, v reflect.Value) error {
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			return setTenant(ctx, v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := setTenant(ctx, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		t := FindTenant(v.Type())
+		if t == nil {
+			return nil
+		}
+		tenant, err := t.Value(ctx)
+		if err != nil {
+			return err
+		}
+		field := v.Field(t.Index)
+		fieldType := field.Type()
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		x := reflect.ValueOf(tenant)
+		if !x.Type().ConvertibleTo(fieldType) || (fieldType.Kind() == reflect.String && isNumber(x.Kind())) {
+			// a number is converted to a string as a rune, such as 65 to "A"
+			return fmt.Errorf("cannot set tenant of type %T to field of type %s", tenant, fieldType)
+		}
+		x = x.Convert(fieldType)
+		if !v.CanSet() {
+			// a model passed by value must already have the tenant of ctx
+			current := reflect.Indirect(field)
+			if current.IsValid() && current.Interface() == x.Interface() {
+				return nil
+			}
+			return fmt.Errorf("cannot set tenant of %s, which is not passed by pointer", v.Type())
+		}
+		if field.Kind() == reflect.Ptr {
+			p := reflect.New(fieldType)
+			p.Elem().Set(x)
+			x = p
+		}
+		field.Set(x)
+	}
+	return nil
+}
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// upsertColumn returns true if column is updated by an upsert, which never changes the tenant of a row.
+func (t *Tenant) upsertColumn(column string) bool {
+	return t == nil || column != t.Column
+}
+
+// excluded returns the condition of the conflict update of an upsert, so that the row of another tenant is not updated.
+func (t *Tenant) excluded(d Dialect, table string) string {
+	column := QuoteName(d, t.Column)
+	return QuoteName(d, table+"."+t.Column) + " = EXCLUDED." + column
+}
+
+// matched returns the condition of a merge, by which the row of another tenant is not matched.
+func (t *Tenant) matched(d Dialect, target string, source string) string {
+	column := QuoteName(d, t.Column)
+	return target + "." + column + " = " + source + "." + column
+}
+
+// ifSameTenant returns the value of the update of column by "on duplicate key update", which is value for the row of the same tenant,
+// or else the current value, so that the row of another tenant is not changed.
+func (t *Tenant) ifSameTenant(d Dialect, column string, value string) string {
+	if t == nil {
+		return value
+	}
+	c := QuoteName(d, t.Column)
+	return "IF(" + c + " = VALUES(" + c + "), " + value + ", " + QuoteName(d, column) + ")"
+}
@@ -0,0 +1,173 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type tenantItem struct {
+	Id     string `json:"id" gorm:"column:id;primary_key"`
+	Tenant string `json:"tenant" gorm:"column:tenant_id;tenant"`
+}
+type tenantIdItem struct {
+	Id     string `json:"id" gorm:"column:id;primary_key"`
+	Tenant *int64 `json:"tenant" gorm:"column:tenant_id;tenant"`
+}
+
+func TestSetTenant(t *testing.T) {
+	item := &tenantItem{Id: "1"}
+	if err := SetTenant(WithTenant(context.Background(), "t1"), item); err != nil || item.Tenant != "t1" {
+		t.Errorf("SetTenant() = %v, tenant %q, want t1", err, item.Tenant)
+	}
+	idItem := &tenantIdItem{Id: "1"}
+	if err := SetTenant(WithTenant(context.Background(), 65), idItem); err != nil || idItem.Tenant == nil || *idItem.Tenant != 65 {
+		t.Errorf("SetTenant() = %v, tenant %v, want 65", err, idItem.Tenant)
+	}
+	item = &tenantItem{Id: "1"}
+	if err := SetTenant(WithTenant(context.Background(), 65), item); err == nil || item.Tenant != "" {
+		t.Errorf("SetTenant() of a number to a string field = %v, tenant %q, want an error", err, item.Tenant)
+	}
+	if err := SetTenant(context.Background(), &tenantItem{Id: "1"}); !errors.Is(err, ErrTenantRequired) {
+		t.Errorf("SetTenant() without tenant = %v, want ErrTenantRequired", err)
+	}
+}
+
+func TestSearchRequiresTenant(t *testing.T) {
+	built := false
+	b := NewSearchBuilderWithError(nil, reflect.TypeOf(tenantItem{}), func(ctx context.Context, sm interface{}) (string, []interface{}, error) {
+		built = true
+		return "", nil, nil
+	})
+	var items []tenantItem
+	if _, err := b.Search(context.Background(), &struct{}{}, &items, 1, 10); !errors.Is(err, ErrTenantRequired) || built {
+		t.Errorf("Search() without tenant = %v, want ErrTenantRequired before the query is built", err)
+	}
+}
+
+type savedTenantItem struct {
+	Id     string `json:"id" gorm:"column:id;primary_key"`
+	Name   string `json:"name" gorm:"column:name"`
+	Tenant string `json:"tenant" gorm:"column:tenant_id;tenant"`
+}
+
+func TestBuildSaveScopesTenant(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		guard   string
+		update  string
+	}{
+		{PostgresDialect{}, ` WHERE "items"."tenant_id" = EXCLUDED."tenant_id"`, `, "tenant_id" = EXCLUDED`},
+		{CockroachDialect{}, ` WHERE "items"."tenant_id" = EXCLUDED."tenant_id"`, `, "tenant_id" = EXCLUDED`},
+		{SqliteDialect{}, ` WHERE "items"."tenant_id" = EXCLUDED."tenant_id"`, `, "tenant_id" = EXCLUDED`},
+		{MySQLDialect{}, "`name` = IF(`tenant_id` = VALUES(`tenant_id`), ?, `name`)", "`tenant_id` = IF"},
+		{MssqlDialect{}, "[items].[tenant_id] = temp.[tenant_id]", "[tenant_id] = temp.[tenant_id],"},
+		{OracleDialect{}, `a."TENANT_ID" = temp."TENANT_ID"`, `SET a."TENANT_ID"`},
+	}
+	for _, tt := range tests {
+		db := openDialect(tt.dialect)
+		query, _, err := BuildSave(db, "items", &savedTenantItem{Id: "1", Name: "a", Tenant: "t1"})
+		if err != nil || !strings.Contains(query, tt.guard) || strings.Contains(query, tt.update) {
+			t.Errorf("%T: BuildSave() = %q, %v, want the tenant guard %s without the update of the tenant", tt.dialect, query, err, tt.guard)
+		}
+		db.Close()
+	}
+}
+
+func TestBuildSaveBatchScopesTenant(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		guard   string
+	}{
+		{PostgresDialect{}, ` where "items"."tenant_id" = EXCLUDED."tenant_id"`},
+		{CockroachDialect{}, ` where "items"."tenant_id" = EXCLUDED."tenant_id"`},
+		{SqliteDialect{}, ` where "items"."tenant_id" = EXCLUDED."tenant_id"`},
+		{MySQLDialect{}, "`name`=IF(`tenant_id` = VALUES(`tenant_id`), ?, `name`)"},
+		{MssqlDialect{}, "[items].[tenant_id] = temp.[tenant_id]"},
+		{OracleDialect{}, `a."TENANT_ID" = temp."TENANT_ID"`},
+	}
+	for _, tt := range tests {
+		db := openDialect(tt.dialect)
+		stmts, err := BuildSaveBatch(db, "items", []savedTenantItem{{Id: "1", Name: "a", Tenant: "t1"}})
+		if err != nil || len(stmts) != 1 || !strings.Contains(stmts[0].Query, tt.guard) || strings.Contains(strings.ToLower(stmts[0].Query), "tenant_id\"=") || strings.Contains(stmts[0].Query, "`tenant_id`=") {
+			t.Errorf("%T: BuildSaveBatch() = %v, %v, want the tenant guard %s without the update of the tenant", tt.dialect, stmts, err, tt.guard)
+		}
+		db.Close()
+	}
+}
+
+// recordConnector records the statements, which affect no row, as the upsert of a row of another tenant.
+type recordConnector struct {
+	args    *[]driver.NamedValue
+	queries *[]string
+}
+
+func (c recordConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return recordConn(c), nil
+}
+func (c recordConnector) Driver() driver.Driver {
+	return nil
+}
+
+type recordConn recordConnector
+
+func (c recordConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (c recordConn) Close() error {
+	return nil
+}
+func (c recordConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+func (c recordConn) Commit() error {
+	return nil
+}
+func (c recordConn) Rollback() error {
+	return nil
+}
+func (c recordConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.args != nil {
+		*c.args = append(*c.args, args...)
+	}
+	if c.queries != nil {
+		*c.queries = append(*c.queries, query)
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func TestSaveManySetsTenant(t *testing.T) {
+	var args []driver.NamedValue
+	db := sql.OpenDB(recordConnector{args: &args})
+	SetDialect(db, PostgresDialect{})
+	defer db.Close()
+	items := []savedTenantItem{{Id: "1", Name: "a"}, {Id: "2", Name: "b", Tenant: "t1"}}
+	if _, err := SaveMany(WithTenant(context.Background(), "t2"), db, "items", items); err != nil {
+		t.Fatalf("SaveMany() error = %v", err)
+	}
+	for _, item := range items {
+		if item.Tenant != "t2" {
+			t.Errorf("SaveMany() tenant of %s = %q, want t2", item.Id, item.Tenant)
+		}
+	}
+	tenants := 0
+	for _, arg := range args {
+		if arg.Value == "t2" {
+			tenants++
+		}
+	}
+	if tenants != 2 {
+		t.Errorf("SaveMany() args = %v, want the tenant t2 of each row", args)
+	}
+	if _, err := SaveMany(context.Background(), db, "items", items); !errors.Is(err, ErrTenantRequired) {
+		t.Errorf("SaveMany() without tenant = %v, want ErrTenantRequired", err)
+	}
+	count, err := Save(WithTenant(context.Background(), "t2"), db, "items", &savedTenantItem{Id: "1", Name: "a"})
+	if err != nil || count != 0 {
+		t.Errorf("Save() of the row of another tenant = %d, %v, want 0 rows", count, err)
+	}
+}
@@ -0,0 +1,166 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"sync/atomic"
+)
+
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// the key includes the database, so a transaction of one database is never used for another one
+type txKey struct {
+	db *sql.DB
+}
+
+func WithTx(ctx context.Context, db *sql.DB, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txKey{db: db}, tx)
+}
+func GetTx(ctx context.Context, db *sql.DB) *sql.Tx {
+	if ctx == nil {
+		return nil
+	}
+	tx, ok := ctx.Value(txKey{db: db}).(*sql.Tx)
+	if ok {
+		return tx
+	}
+	return nil
+}
+
+// GetExecutor returns the transaction of db stored in ctx, or db itself if there is no transaction.
+// If db has a router, db is the database of the tenant of ctx.
+// Outside of a transaction, statements are retried by the retry policy of db, if any.
+func GetExecutor(ctx context.Context, db *sql.DB) Executor {
+	tx := GetTx(ctx, db)
+	if tx != nil {
+		return tx
+	}
+	db, err := Route(ctx, db)
+	if err != nil {
+		return routeError(err)
+	}
+	if c := GetCluster(db); c != nil {
+		return &clusterExecutor{cluster: c, primary: newExecutor(db)}
+	}
+	return newExecutor(db)
+}
+
+// newExecutor returns db, which retries the statements by its retry policy, if any.
+func newExecutor(db *sql.DB) Executor {
+	if p := GetRetryPolicy(db); p != nil {
+		return &retryExecutor{db: db, driver: GetDriver(db), policy: p}
+	}
+	return db
+}
+
+// RunInTx runs fn in a transaction of db, which is stored in the context passed to fn.
+// The transaction is committed if fn returns nil, and rolled back if fn returns an error or panics.
+// If ctx already holds a transaction of db, fn runs in a savepoint of it instead.
+// A new transaction is retried as a whole by the retry policy of db, so fn must be safe to call again.
+func RunInTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error, options ...*sql.TxOptions) error {
+	var opts *sql.TxOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	return RetryTx(ctx, db, func() error {
+		return runInTx(ctx, db, fn, opts)
+	})
+}
+func runInTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error, opts *sql.TxOptions) (err error) {
+	tx, er0 := BeginTx(ctx, db, opts)
+	if er0 != nil {
+		return HandleError(db, er0)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = HandleError(db, tx.Commit())
+		}
+	}()
+	err = HandleError(db, fn(WithTx(ctx, db, tx.Tx)))
+	return err
+}
+
+// Transaction is used by the batch functions: it is either a new transaction, or a savepoint of the transaction of the context.
+type Transaction struct {
+	*sql.Tx
+	ctx       context.Context
+	driver    string
+	savepoint string
+}
+
+var savepointSeq uint64
+
+func Begin(ctx context.Context, db *sql.DB) (*Transaction, error) {
+	return BeginTx(ctx, db, nil)
+}
+func BeginTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (*Transaction, error) {
+	tx := GetTx(ctx, db)
+	if tx != nil {
+		driver := GetDriver(db)
+		name := "sp_" + strconv.FormatUint(atomic.AddUint64(&savepointSeq, 1), 10)
+		_, err := tx.ExecContext(ctx, BuildSavepoint(driver, name))
+		if err != nil {
+			return nil, err
+		}
+		return &Transaction{Tx: tx, ctx: ctx, driver: driver, savepoint: name}, nil
+	}
+	target, err := Route(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	tx, err = target.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Transaction{Tx: tx, ctx: ctx}, nil
+}
+func (t *Transaction) Commit() error {
+	if len(t.savepoint) == 0 {
+		return t.Tx.Commit()
+	}
+	query := BuildReleaseSavepoint(t.driver, t.savepoint)
+	if len(query) == 0 {
+		return nil
+	}
+	_, err := t.Tx.ExecContext(t.ctx, query)
+	return err
+}
+func (t *Transaction) Rollback() error {
+	if len(t.savepoint) == 0 {
+		return t.Tx.Rollback()
+	}
+	_, err := t.Tx.ExecContext(t.ctx, BuildRollbackToSavepoint(t.driver, t.savepoint))
+	return err
+}
+
+func BuildSavepoint(driver string, name string) string {
+	if driver == DriverMssql {
+		return "save transaction " + name
+	}
+	return "savepoint " + name
+}
+func BuildRollbackToSavepoint(driver string, name string) string {
+	if driver == DriverMssql {
+		return "rollback transaction " + name
+	}
+	return "rollback to savepoint " + name
+}
+
+// BuildReleaseSavepoint returns an empty string for oracle and mssql, which release savepoints at the end of the transaction only.
+func BuildReleaseSavepoint(driver string, name string) string {
+	if driver == DriverMssql || driver == DriverOracle {
+		return ""
+	}
+	return "release savepoint " + name
+}
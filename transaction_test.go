@@ -0,0 +1,29 @@
+package sql
+
+import "testing"
+
+func TestBuildSavepoint(t *testing.T) {
+	tests := []struct {
+		driver   string
+		save     string
+		rollback string
+		release  string
+	}{
+		{DriverPostgres, "savepoint sp1", "rollback to savepoint sp1", "release savepoint sp1"},
+		{DriverMysql, "savepoint sp1", "rollback to savepoint sp1", "release savepoint sp1"},
+		{DriverSqlite3, "savepoint sp1", "rollback to savepoint sp1", "release savepoint sp1"},
+		{DriverMssql, "save transaction sp1", "rollback transaction sp1", ""},
+		{DriverOracle, "savepoint sp1", "rollback to savepoint sp1", ""},
+	}
+	for _, tt := range tests {
+		if got := BuildSavepoint(tt.driver, "sp1"); got != tt.save {
+			t.Errorf("BuildSavepoint(%q) = %q, want %q", tt.driver, got, tt.save)
+		}
+		if got := BuildRollbackToSavepoint(tt.driver, "sp1"); got != tt.rollback {
+			t.Errorf("BuildRollbackToSavepoint(%q) = %q, want %q", tt.driver, got, tt.rollback)
+		}
+		if got := BuildReleaseSavepoint(tt.driver, "sp1"); got != tt.release {
+			t.Errorf("BuildReleaseSavepoint(%q) = %q, want %q", tt.driver, got, tt.release)
+		}
+	}
+}
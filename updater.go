@@ -10,6 +10,7 @@ type Updater struct {
 	tableName  string
 	BuildParam func(i int) string
 	Map        func(ctx context.Context, model interface{}) (interface{}, error)
+	Audit      *Audit
 }
 func NewUpdater(db *sql.DB, tableName string, options ...func(context.Context, interface{}) (interface{}, error)) *Updater {
 	var mp func(context.Context, interface{}) (interface{}, error)
@@ -25,10 +26,11 @@ func NewSqlUpdater(db *sql.DB, tableName string, mp func(context.Context, interf
 	} else {
 		buildParam = GetBuild(db)
 	}
-	return &Updater{db: db, tableName: tableName, BuildParam: buildParam, Map: mp}
+	return &Updater{db: db, tableName: tableName, BuildParam: buildParam, Map: mp, Audit: NewAudit(GetAuditUser(db))}
 }
 
 func (w *Updater) Write(ctx context.Context, model interface{}) error {
+	w.Audit.OnUpdate(ctx, model)
 	if w.Map != nil {
 		m2, er0 := w.Map(ctx, model)
 		if er0 != nil {
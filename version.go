@@ -0,0 +1,168 @@
+package sql
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// VersionStrategy generates the versions of a row for optimistic locking.
+type VersionStrategy interface {
+	// Next returns the version after current, which is nil for a new row.
+	Next(current interface{}) (interface{}, error)
+}
+
+// IncrementVersion increases an integer version by 1, from 1 for a new row.
+type IncrementVersion struct{}
+
+func (v IncrementVersion) Next(current interface{}) (interface{}, error) {
+	switch c := current.(type) {
+	case nil:
+		return int64(1), nil
+	case int:
+		return int64(c) + 1, nil
+	case int32:
+		return int64(c) + 1, nil
+	case int64:
+		return c + 1, nil
+	case float64:
+		return int64(c) + 1, nil
+	case json.Number:
+		i, err := c.Int64()
+		return i + 1, err
+	case string:
+		i, err := strconv.ParseInt(c, 10, 64)
+		return i + 1, err
+	default:
+		return nil, fmt.Errorf("cannot increase version of type %T", current)
+	}
+}
+
+// TimestampVersion uses the current time in UTC as the version.
+// Precision must be the precision of the column, which is time.Microsecond by default, so that the stored version equals the one of the model.
+type TimestampVersion struct {
+	Precision time.Duration
+}
+
+func (v TimestampVersion) Next(current interface{}) (interface{}, error) {
+	precision := v.Precision
+	if precision <= 0 {
+		precision = time.Microsecond
+	}
+	next := time.Now().UTC().Truncate(precision)
+	if c, ok := current.(time.Time); ok && !next.After(c) {
+		next = c.Add(precision)
+	}
+	return next, nil
+}
+
+// TokenVersion uses a random hex string as the version.
+type TokenVersion struct{}
+
+func (v TokenVersion) Next(current interface{}) (interface{}, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GetVersionStrategy returns the strategy of a version field by its type: TimestampVersion for time.Time, TokenVersion for string and IncrementVersion for the others.
+func GetVersionStrategy(fieldType reflect.Type) VersionStrategy {
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	if fieldType == reflect.TypeOf(time.Time{}) {
+		return TimestampVersion{}
+	}
+	if fieldType.Kind() == reflect.String {
+		return TokenVersion{}
+	}
+	return IncrementVersion{}
+}
+func getVersionStrategy(model interface{}, versionIndex int) VersionStrategy {
+	return GetVersionStrategy(reflect.Indirect(reflect.ValueOf(model)).Type().Field(versionIndex).Type)
+}
+
+// getVersion returns the version of model, or nil if it is a nil pointer.
+func getVersion(model interface{}, versionIndex int) interface{} {
+	field := reflect.Indirect(reflect.ValueOf(model)).Field(versionIndex)
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+	}
+	return field.Interface()
+}
+func setVersion(model interface{}, versionIndex int, version interface{}) error {
+	field := reflect.Indirect(reflect.ValueOf(model)).Field(versionIndex)
+	if version == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	fieldType := field.Type()
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	v := reflect.ValueOf(version)
+	if !v.Type().ConvertibleTo(fieldType) {
+		return fmt.Errorf("cannot set version of type %T to field of type %s", version, fieldType)
+	}
+	v = v.Convert(fieldType)
+	if field.Kind() == reflect.Ptr {
+		p := reflect.New(fieldType)
+		p.Elem().Set(v)
+		v = p
+	}
+	field.Set(v)
+	return nil
+}
+
+// toVersion converts the version of a patch, which is decoded from json, to the type of the version field.
+func toVersion(version interface{}, fieldType reflect.Type) interface{} {
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	switch v := version.(type) {
+	case string:
+		if fieldType == reflect.TypeOf(time.Time{}) {
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				return t
+			}
+		}
+	case float64:
+		if fieldType.Kind() >= reflect.Int && fieldType.Kind() <= reflect.Uint64 {
+			return int64(v)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+	}
+	return version
+}
+
+// checkVersion is called when a versioned write updates no row, to return ErrNotFound or ErrVersionConflict with the current version.
+func checkVersion(ctx context.Context, db *sql.DB, table string, versionColumn string, keys map[string]interface{}) error {
+	dialect := GetDialect(db)
+	query, values := buildSelectByKeys(dialect, table, QuoteName(dialect, versionColumn), keys)
+	var version interface{}
+	err := GetExecutor(ctx, db).QueryRowContext(ctx, query, values...).Scan(&version)
+	if err == sql.ErrNoRows {
+		return &VersionError{Kind: ErrNotFound}
+	}
+	if err != nil {
+		return HandleError(db, err)
+	}
+	if b, ok := version.([]byte); ok {
+		version = string(b)
+	}
+	return &VersionError{Kind: ErrVersionConflict, Version: version}
+}
@@ -0,0 +1,45 @@
+package sql
+
+import (
+	"errors"
+	"testing"
+)
+
+type versionedItem struct {
+	Id      string `json:"id" gorm:"column:id;primary_key"`
+	Name    string `json:"name" gorm:"column:name"`
+	Version int64  `json:"version" gorm:"column:version"`
+}
+
+type failedVersion struct{}
+
+func (v failedVersion) Next(current interface{}) (interface{}, error) {
+	return nil, errors.New("no version")
+}
+
+func TestBuildWithVersionError(t *testing.T) {
+	item := &versionedItem{Id: "1", Name: "a", Version: 3}
+	if _, _, err := buildInsertWithVersion("items", item, 1, 2, failedVersion{}, BuildDollarParam, PostgresDialect{}); err == nil {
+		t.Error("buildInsertWithVersion() returns no error of the version strategy")
+	}
+	if _, _, err := buildUpdateWithVersion("items", item, 0, 2, "version", failedVersion{}, BuildDollarParam, PostgresDialect{}); err == nil {
+		t.Error("buildUpdateWithVersion() returns no error of the version strategy")
+	}
+	patch := map[string]interface{}{"id": "1", "name": "a", "version": "x"}
+	columns := map[string]string{"id": "id", "name": "name", "version": "version"}
+	if _, _, err := buildPatchWithVersion("items", patch, columns, []string{"id"}, []string{"id"}, BuildDollarParam, "version", "version", IncrementVersion{}, PostgresDialect{}); err == nil {
+		t.Error("buildPatchWithVersion() returns no error of an invalid version")
+	}
+}
+
+func TestBuildInsertWithVersion(t *testing.T) {
+	item := &versionedItem{Id: "1", Name: "a"}
+	query, values, err := buildInsertWithVersion("items", item, 1, 2, IncrementVersion{}, BuildDollarParam, PostgresDialect{})
+	if err != nil {
+		t.Fatalf("buildInsertWithVersion() error = %v", err)
+	}
+	want := `insert into "items"("id","name","version")values($1,$2,1)`
+	if query != want || len(values) != 2 || item.Version != 1 {
+		t.Errorf("buildInsertWithVersion() = %q %v, version %d, want %q with version 1", query, values, item.Version, want)
+	}
+}
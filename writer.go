@@ -14,6 +14,9 @@ type Writer struct {
 	versionField   string
 	versionIndex   int
 	versionDBField string
+	// VersionStrategy generates the versions, by the type of the version field by default.
+	VersionStrategy VersionStrategy
+	Audit           *Audit
 }
 
 func NewWriterWithVersion(db *sql.DB, tableName string, modelType reflect.Type, versionField string, options ...Mapper) *Writer {
@@ -37,10 +40,10 @@ func NewSqlWriterWithVersion(db *sql.DB, tableName string, modelType reflect.Typ
 			if !exist {
 				dbFieldName = strings.ToLower(versionField)
 			}
-			return &Writer{Loader: loader, versionField: versionField, versionIndex: index, versionDBField: dbFieldName}
+			return &Writer{Loader: loader, Mapper: mapper, versionField: versionField, versionIndex: index, versionDBField: dbFieldName, VersionStrategy: GetVersionStrategy(modelType.Field(index).Type), Audit: NewAudit(GetAuditUser(db))}
 		}
 	}
-	return &Writer{Loader: loader, Mapper: mapper, versionField: versionField, versionIndex: -1}
+	return &Writer{Loader: loader, Mapper: mapper, versionField: versionField, versionIndex: -1, Audit: NewAudit(GetAuditUser(db))}
 }
 func NewWriterWithMap(db *sql.DB, tableName string, modelType reflect.Type, mapper Mapper, options ...func(i int) string) *Writer {
 	return NewSqlWriterWithVersion(db, tableName, modelType, "", mapper, options...)
@@ -54,40 +57,73 @@ func NewWriter(db *sql.DB, tableName string, modelType reflect.Type, options ...
 }
 
 func (s *Writer) Insert(ctx context.Context, model interface{}) (int64, error) {
+	s.Audit.OnInsert(ctx, model)
 	if s.Mapper != nil {
 		m2, err := s.Mapper.ModelToDb(ctx, model)
 		if err != nil {
 			return 0, err
 		}
 		if s.versionIndex >= 0 {
-			return InsertWithVersion(ctx, s.Database, s.table, m2, s.versionIndex, s.BuildParam)
+			return insertWithVersion(ctx, s.Database, s.table, m2, s.versionIndex, s.VersionStrategy, s.BuildParam)
 		}
 		return Insert(ctx, s.Database, s.table, m2, s.BuildParam)
 	}
 	if s.versionIndex >= 0 {
-		return InsertWithVersion(ctx, s.Database, s.table, model, s.versionIndex, s.BuildParam)
+		return insertWithVersion(ctx, s.Database, s.table, model, s.versionIndex, s.VersionStrategy, s.BuildParam)
 	}
 	return Insert(ctx, s.Database, s.table, model, s.BuildParam)
 }
 
 func (s *Writer) Update(ctx context.Context, model interface{}) (int64, error) {
+	s.Audit.OnUpdate(ctx, model)
 	if s.Mapper != nil {
 		m2, err := s.Mapper.ModelToDb(ctx, &model)
 		if err != nil {
 			return 0, err
 		}
 		if s.versionIndex >= 0 {
-			return UpdateWithVersion(ctx, s.Database, s.table, m2, s.versionIndex, s.BuildParam)
+			return updateWithVersion(ctx, s.Database, s.table, m2, s.versionIndex, s.VersionStrategy, s.BuildParam, false)
 		}
 		return Update(ctx, s.Database, s.table, m2, s.BuildParam)
 	}
 	if s.versionIndex >= 0 {
-		return UpdateWithVersion(ctx, s.Database, s.table, model, s.versionIndex, s.BuildParam)
+		return updateWithVersion(ctx, s.Database, s.table, model, s.versionIndex, s.VersionStrategy, s.BuildParam, false)
 	}
 	return Update(ctx, s.Database, s.table, model, s.BuildParam)
 }
 
+// UpdateReturning updates model and fills it with the stored row, so a second Load is not needed.
+func (s *Writer) UpdateReturning(ctx context.Context, model interface{}) (int64, error) {
+	s.Audit.OnUpdate(ctx, model)
+	if s.Mapper != nil {
+		m2, err := s.Mapper.ModelToDb(ctx, model)
+		if err != nil {
+			return 0, err
+		}
+		model = m2
+	}
+	var count int64
+	var err error
+	if s.versionIndex >= 0 {
+		count, err = updateWithVersion(ctx, s.Database, s.table, model, s.versionIndex, s.VersionStrategy, s.BuildParam, true)
+	} else {
+		count, err = UpdateReturning(ctx, s.Database, s.table, model, s.BuildParam)
+	}
+	if err == nil && count > 0 && s.Mapper != nil {
+		_, err = s.Mapper.DbToModel(ctx, model)
+	}
+	return count, err
+}
+
 func (s *Writer) Save(ctx context.Context, model map[string]interface{}) (int64, error) {
+	s.Audit.OnSave(ctx, model, s.modelType)
+	if s.tenant != nil {
+		tenant, err := s.tenant.Value(ctx)
+		if err != nil {
+			return 0, err
+		}
+		model[s.tenant.Json] = tenant
+	}
 	if s.Mapper != nil {
 		m2, err := s.Mapper.ModelToDb(ctx, &model)
 		if err != nil {
@@ -98,17 +134,64 @@ func (s *Writer) Save(ctx context.Context, model map[string]interface{}) (int64,
 	return Save(ctx, s.Database, s.table, model)
 }
 
+// Delete soft deletes the row if the model has a soft-delete column, unless the writer is unscoped.
 func (s *Writer) Delete(ctx context.Context, id interface{}) (int64, error) {
-	l := len(s.keys)
-	if l == 1 {
-		return Delete(ctx, s.Database, s.table, BuildQueryById(id, s.modelType, s.keys[0]), s.BuildParam)
+	query, err := s.buildQueryById(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if s.softDelete != nil && !s.unscoped {
+		return MarkDeleted(ctx, s.Database, s.table, query, s.softDelete, s.BuildParam)
+	}
+	return Delete(ctx, s.Database, s.table, query, s.BuildParam)
+}
+
+// HardDelete deletes the row, even if the model has a soft-delete column.
+func (s *Writer) HardDelete(ctx context.Context, id interface{}) (int64, error) {
+	query, err := s.buildQueryById(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	return Delete(ctx, s.Database, s.table, query, s.BuildParam)
+}
+
+// Restore restores the soft deleted row.
+func (s *Writer) Restore(ctx context.Context, id interface{}) (int64, error) {
+	query, err := s.buildQueryById(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	return Restore(ctx, s.Database, s.table, query, s.softDelete, s.BuildParam)
+}
+
+// Unscoped returns a copy of the writer, which loads the soft deleted rows and deletes the rows permanently.
+func (s *Writer) Unscoped() *Writer {
+	w := *s
+	w.Loader = s.Loader.Unscoped()
+	return &w
+}
+
+// buildQueryById builds the query of the row by id, which is scoped by the tenant of ctx if the model has a tenant column.
+func (s *Writer) buildQueryById(ctx context.Context, id interface{}) (map[string]interface{}, error) {
+	var query map[string]interface{}
+	if len(s.keys) == 1 {
+		query = BuildQueryById(id, s.modelType, s.keys[0])
 	} else {
 		ids := id.(map[string]interface{})
-		return Delete(ctx, s.Database, s.table, MapToGORM(ids, s.modelType), s.BuildParam)
+		query = MapToGORM(ids, s.modelType)
+	}
+	if s.tenant != nil {
+		tenant, err := s.tenant.Value(ctx)
+		if err != nil {
+			return nil, err
+		}
+		query[s.tenant.Column] = tenant
 	}
+	return query, nil
 }
 
 func (s *Writer) Patch(ctx context.Context, model map[string]interface{}) (int64, error) {
+	s.Audit.OnPatch(ctx, model, s.modelType)
 	if s.Mapper != nil {
 		_, err := s.Mapper.ModelToDb(ctx, &model)
 		if err != nil {
@@ -116,5 +199,31 @@ func (s *Writer) Patch(ctx context.Context, model map[string]interface{}) (int64
 		}
 	}
 	MapToDB(&model, s.modelType)
+	if s.versionIndex >= 0 {
+		return patchWithVersion(ctx, s.Database, s.table, model, s.modelType, s.versionIndex, s.VersionStrategy, s.BuildParam, nil)
+	}
 	return Patch(ctx, s.Database, s.table, model, s.modelType, s.BuildParam)
 }
+
+// PatchReturning patches the row and fills result, which is a pointer to a struct of the model type, with the stored row.
+func (s *Writer) PatchReturning(ctx context.Context, model map[string]interface{}, result interface{}) (int64, error) {
+	s.Audit.OnPatch(ctx, model, s.modelType)
+	if s.Mapper != nil {
+		_, err := s.Mapper.ModelToDb(ctx, &model)
+		if err != nil {
+			return 0, err
+		}
+	}
+	MapToDB(&model, s.modelType)
+	var count int64
+	var err error
+	if s.versionIndex >= 0 {
+		count, err = patchWithVersion(ctx, s.Database, s.table, model, s.modelType, s.versionIndex, s.VersionStrategy, s.BuildParam, result)
+	} else {
+		count, err = PatchReturning(ctx, s.Database, s.table, model, s.modelType, result, s.BuildParam)
+	}
+	if err == nil && count > 0 && s.Mapper != nil {
+		_, err = s.Mapper.DbToModel(ctx, result)
+	}
+	return count, err
+}